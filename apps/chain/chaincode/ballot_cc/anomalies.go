@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// anomalousVoteIndex lists the votes of an election flagged as anomalous.
+const anomalousVoteIndex = "anomaly~vote"
+
+// optionCountKey holds the running number of votes for one option of an
+// election. It is only maintained when MaxVotesPerOption is set, since every
+// vote for the option rewrites it.
+func optionCountKey(electionID, optionKey string) string {
+	return fmt.Sprintf("optioncount:%s:%s", electionID, optionKey)
+}
+
+// flagOptionBurst counts votes towards a vote's option, the vote itself plus
+// any recorded with it for a proxy's delegators, and marks it Anomalous,
+// indexing it for GetAnomalousVotes, once the option exceeds the election's
+// MaxVotesPerOption. Votes are never rejected for this.
+func flagOptionBurst(ctx contractapi.TransactionContextInterface, election *Election, vote *VoteCommitment, votes int) error {
+	countKey := voteOptionCountKey(election.ElectionID, vote)
+	count, err := readCounter(ctx, countKey)
+	if err != nil {
+		return err
+	}
+	if err := addToCounter(ctx, countKey, votes); err != nil {
+		return err
+	}
+	if count+votes <= election.MaxVotesPerOption {
+		return nil
+	}
+
+	vote.Anomalous = true
+	indexKey, err := ctx.GetStub().CreateCompositeKey(anomalousVoteIndex, []string{election.ElectionID, vote.CommitmentHash})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(indexKey, []byte{0x00})
+}
+
+// unflagOptionBurst reverses flagOptionBurst for an invalidated vote: it no
+// longer counts towards its option and, if it was flagged, is dropped from the
+// anomaly index. The vote record keeps its Anomalous flag.
+func unflagOptionBurst(ctx contractapi.TransactionContextInterface, election *Election, vote *VoteCommitment) error {
+	if election.MaxVotesPerOption > 0 && !vote.Sealed && vote.optionIndexed() {
+		if err := addToCounter(ctx, voteOptionCountKey(election.ElectionID, vote), -1); err != nil {
+			return err
+		}
+	}
+	if !vote.Anomalous {
+		return nil
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(anomalousVoteIndex, []string{election.ElectionID, vote.CommitmentHash})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(indexKey)
+}
+
+// voteOptionCountKey is the optionCountKey a vote counts towards.
+func voteOptionCountKey(electionID string, vote *VoteCommitment) string {
+	optionKey := vote.OptionID
+	if vote.ContestID != "" {
+		optionKey = contestTallyKey(vote.ContestID, vote.OptionID)
+	}
+	return optionCountKey(electionID, optionKey)
+}
+
+// GetAnomalousVotes returns the votes of an election flagged as anomalous, for
+// post-hoc review.
+func (c *BallotContract) GetAnomalousVotes(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) ([]VoteCommitment, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(anomalousVoteIndex, []string{electionID})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	votes := []VoteCommitment{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(record.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) != 2 {
+			continue
+		}
+
+		key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{electionID, parts[1]})
+		if err != nil {
+			return nil, err
+		}
+		bytes, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, err
+		}
+		if bytes == nil {
+			continue
+		}
+
+		var vote VoteCommitment
+		if err := unmarshalState(bytes, &vote); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+
+	return votes, nil
+}
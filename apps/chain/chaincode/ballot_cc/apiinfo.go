@@ -0,0 +1,725 @@
+package main
+
+// Roles a transaction may require of its caller, as listed by GetAPIInfo.
+const (
+	// RoleContractAdmin is the identity that called InitLedger.
+	RoleContractAdmin = "contract-admin"
+	// RoleAdministrator is a member of an administrator organization.
+	RoleAdministrator = "administrator"
+	// RoleAuditor is a member of an auditor organization.
+	RoleAuditor = "auditor"
+	// RoleOfficial is a member of an election official organization.
+	RoleOfficial = "official"
+	// RoleCertifier is a member of an allowed certifier organization, or an
+	// election official while no certifier list is set.
+	RoleCertifier = "certifier"
+	// RoleTrustee is the client identity listed for one of an election's
+	// trustees.
+	RoleTrustee = "trustee"
+)
+
+// APIParameter describes one argument of a transaction.
+type APIParameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// APIFunction describes a transaction for integrators: its arguments in
+// order, the roles the caller must hold (any one of them), and whether it is
+// an evaluate (read-only) transaction.
+type APIFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  []APIParameter `json:"parameters"`
+	Roles       []string       `json:"roles,omitempty"`
+	Evaluate    bool           `json:"evaluate,omitempty"`
+}
+
+// commonParameters describes arguments that mean the same in every
+// transaction taking them.
+var commonParameters = map[string]string{
+	"electionID":     "ID of the election",
+	"commitmentHash": "Hex commitment hash",
+	"subjectHash":    "Hashed voter identifier",
+	"optionID":       "ID of the chosen option",
+	"metaJSON":       "JSON object of vote metadata",
+	"metadataJSON":   "JSON object of metadata",
+	"pageSize":       "Maximum number of records to return",
+	"bookmark":       "Bookmark returned by the previous page, or empty to start",
+	"ballotID":       "Client-assigned ballot ID",
+	"timestamp":      "Client-supplied RFC3339 timestamp",
+	"officialID":     "ID of the election official acting",
+	"certifierID":    "ID of the certifying official",
+	"contestID":      "ID of the contest",
+	"collection":     "Name of the private data collection",
+	"stationID":      "ID of the polling station",
+	"merkleRoot":     "Hex Merkle root",
+	"previousRoot":   "Merkle root of the previous anchor, if any",
+	"batchSize":      "Number of log entries under the root",
+	"seconds":        "Number of seconds; zero disables",
+	"max":            "Maximum count; zero is unlimited",
+}
+
+func param(name string) APIParameter {
+	return APIParameter{Name: name, Description: commonParameters[name]}
+}
+
+// apiFunctions is the curated list returned by GetAPIInfo, in alphabetical
+// order. TestAPIInfoMatchesContract keeps it in step with the contract's
+// methods.
+var apiFunctions = []APIFunction{
+	{
+		Name:        "AmendResults",
+		Description: "Supersedes the certified results of a CERTIFIED election.",
+		Parameters:  []APIParameter{param("electionID"), {"newResultsHash", "Hex hash of the amended results"}, {"totalVotes", "Amended total number of votes"}, {"reason", "Why the results are amended"}, param("certifierID")},
+		Roles:       []string{RoleCertifier},
+	},
+	{
+		Name:        "AnchorAuditLogs",
+		Description: "Anchors a Merkle root of audit logs.",
+		Parameters:  []APIParameter{param("electionID"), param("merkleRoot"), param("previousRoot"), {"timestamp", "RFC3339 time of the anchored batch"}, param("batchSize"), param("metadataJSON")},
+	},
+	{
+		Name:        "AnchorStationClose",
+		Description: "Records a polling station's closing hash and ballot count.",
+		Parameters:  []APIParameter{param("electionID"), param("stationID"), {"closingHash", "Hex SHA-256 of the station's ballot box"}, {"ballotCount", "Number of ballots the station holds"}, param("timestamp")},
+	},
+	{
+		Name:        "AnchorVerifiedAuditLogs",
+		Description: "Anchors a Merkle root after checking it against the election's ballot commitments.",
+		Parameters:  []APIParameter{param("electionID"), param("merkleRoot"), param("previousRoot"), {"timestamp", "RFC3339 time of the anchored batch"}, param("batchSize"), param("metadataJSON")},
+	},
+	{
+		Name:        "ArchiveElection",
+		Description: "Deletes the vote and ballot records of a CERTIFIED election in batches, then archives it.",
+		Parameters:  []APIParameter{param("electionID"), {"archiveDigest", "Hex SHA-256 of the off-chain archive export"}, {"confirmation", "The election ID and archive digest, joined by ':'"}},
+		Roles:       []string{RoleAdministrator},
+	},
+	{
+		Name:        "BallotExists",
+		Description: "Reports whether a ballot commitment is recorded in an election.",
+		Parameters:  []APIParameter{param("electionID"), param("commitmentHash")},
+	},
+	{
+		Name:        "BatchSubmitBallotCommitments",
+		Description: "Records many ballot commitments in a single transaction.",
+		Parameters:  []APIParameter{{"commitmentsJSON", "JSON array of BallotSubmission"}},
+	},
+	{
+		Name:        "CastContestVote",
+		Description: "Records a vote in one contest of a multi-contest election.",
+		Parameters:  []APIParameter{param("electionID"), param("contestID"), param("subjectHash"), param("commitmentHash"), param("optionID"), param("metaJSON")},
+	},
+	{
+		Name:        "CastProvisionalVote",
+		Description: "Records a vote whose voter's eligibility is still to be confirmed.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash"), param("commitmentHash"), param("optionID"), param("metaJSON")},
+	},
+	{
+		Name:        "CastRankedVote",
+		Description: "Records a preferential vote.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash"), param("commitmentHash"), {"preferencesJSON", "JSON array of option IDs, most preferred first"}, param("metaJSON")},
+	},
+	{
+		Name:        "CastSignedVote",
+		Description: "Records a vote carrying the voter's Ed25519 signature.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash"), param("commitmentHash"), param("optionID"), param("metaJSON"), {"voterPubKey", "Base64 Ed25519 public key of the voter"}, {"voterSignature", "Base64 signature over \"electionID|commitmentHash|optionID\""}},
+	},
+	{
+		Name:        "CastVote",
+		Description: "Records a vote commitment on ledger.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash"), param("commitmentHash"), param("optionID"), param("metaJSON")},
+	},
+	{
+		Name:        "CastVoteWithNonce",
+		Description: "Records a vote with a client-supplied nonce.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash"), param("commitmentHash"), param("optionID"), param("metaJSON"), {"nonce", "Client-supplied nonce, unique per election"}},
+	},
+	{
+		Name:        "CastWriteInVote",
+		Description: "Records a vote for a free-text candidate that is not one of the election's options.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash"), param("commitmentHash"), {"writeInText", "Free-text candidate name"}, param("metaJSON")},
+	},
+	{
+		Name:        "CertifyResults",
+		Description: "Certifies a CLOSED election's results against its tally snapshot.",
+		Parameters:  []APIParameter{param("electionID"), {"resultsHash", "Canonical hash of the tally snapshot"}, {"totalVotes", "Total number of votes certified"}, {"certifiedAt", "Time of certification"}, param("certifierID"), param("metadataJSON")},
+		Roles:       []string{RoleCertifier},
+	},
+	{
+		Name:        "CheckElectionConsistency",
+		Description: "Checks an election's vote counter, vote options and subject registrations against its votes.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "CloseElection",
+		Description: "Closes an OPEN or PAUSED election and snapshots its tally.",
+		Parameters:  []APIParameter{param("electionID")},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "CombineShares",
+		Description: "Combines the decryption shares of a CLOSED election once enough trustees have submitted.",
+		Parameters:  []APIParameter{param("electionID")},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "ComputeBallotMerkleRoot",
+		Description: "Rebuilds the Merkle root over an election's ballot commitments.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "ComputeResultsHash",
+		Description: "Returns the canonical results hash of an election's tally snapshot.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "ConfirmProvisionalVote",
+		Description: "Counts a pending provisional vote.",
+		Parameters:  []APIParameter{param("electionID"), param("commitmentHash"), param("officialID")},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "CountVotesByOption",
+		Description: "Returns the current number of counted votes per option.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "CreateElection",
+		Description: "Creates a DRAFT election from a JSON ElectionConfig.",
+		Parameters:  []APIParameter{param("electionID"), {"configJSON", "JSON ElectionConfig"}},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "ExpireStaleCommitments",
+		Description: "Marks a sealed election's votes left unrevealed past its reveal TTL as expired, in batches.",
+		Parameters:  []APIParameter{param("electionID")},
+		Roles:       []string{RoleAdministrator},
+	},
+	{
+		Name:        "FileChallenge",
+		Description: "Disputes a vote or ballot commitment and returns the challenge ID.",
+		Parameters:  []APIParameter{param("electionID"), param("commitmentHash"), {"challengerID", "ID of the challenging observer"}, {"reason", "Grounds for the challenge"}},
+	},
+	{
+		Name:        "GetAPIInfo",
+		Description: "Returns this list of transactions with their parameters and required roles.",
+	},
+	{
+		Name:        "GetAllowedCertifiers",
+		Description: "Returns the MSP IDs allowed to certify results.",
+	},
+	{
+		Name:        "GetAnomalousVotes",
+		Description: "Returns the votes flagged as anomalous.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetAttestationKey",
+		Description: "Returns an organization's published attestation public key by key ID.",
+		Parameters:  []APIParameter{{"mspID", "MSP ID of the organization"}, {"keyID", "Hex SHA-256 of the public key"}},
+	},
+	{
+		Name:        "GetAuditSample",
+		Description: "Returns the audit sample selected for an election with seed.",
+		Parameters:  []APIParameter{param("electionID"), {"seed", "Seed the sample was selected with"}},
+	},
+	{
+		Name:        "GetBallotCommitment",
+		Description: "Retrieves a ballot commitment by its hash.",
+		Parameters:  []APIParameter{param("commitmentHash")},
+	},
+	{
+		Name:        "GetBallotCommitmentByBallotID",
+		Description: "Retrieves the ballot commitment recorded for a ballot ID in an election.",
+		Parameters:  []APIParameter{param("electionID"), param("ballotID")},
+	},
+	{
+		Name:        "GetBallotCommitmentCount",
+		Description: "Returns an election's ballot commitment and spoiled ballot counts.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetBallotCommitmentForElection",
+		Description: "Retrieves a ballot commitment from a known election with a single point read of its key.",
+		Parameters:  []APIParameter{param("electionID"), param("commitmentHash")},
+	},
+	{
+		Name:        "GetBallotHistory",
+		Description: "Returns every write to a ballot commitment, newest first.",
+		Parameters:  []APIParameter{param("electionID"), param("commitmentHash")},
+	},
+	{
+		Name:        "GetBallotReceiptHash",
+		Description: "Returns the canonical hash of the ballot commitment stored for a ballot ID.",
+		Parameters:  []APIParameter{param("electionID"), param("ballotID")},
+	},
+	{
+		Name:        "GetBallotsBySubject",
+		Description: "Returns every ballot commitment a subject submitted.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash")},
+	},
+	{
+		Name:        "GetBallotsByTimeRange",
+		Description: "Returns the ballots of one page whose client timestamp falls within a time range.",
+		Parameters:  []APIParameter{param("electionID"), {"startTs", "RFC3339 start of the range, inclusive"}, {"endTs", "RFC3339 end of the range, inclusive"}, param("pageSize"), param("bookmark")},
+	},
+	{
+		Name:        "GetContractVersion",
+		Description: "Returns the chaincode release and record schema version.",
+	},
+	{
+		Name:        "GetDecryptionShares",
+		Description: "Returns an election's decryption shares by trustee ID.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetElection",
+		Description: "Returns an election record.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetElectionAuditSummary",
+		Description: "Compares an election's certified, computed and snapshot vote totals and audit root.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetElectionStats",
+		Description: "Returns an election's status, turnout, ballot counts and tally.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetElectionTimeline",
+		Description: "Returns an election's key events in chronological order.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetEventLog",
+		Description: "Returns a page of an election's event log entries after a sequence number.",
+		Parameters:  []APIParameter{param("electionID"), {"fromSeq", "Sequence number to continue after, or 0 to start"}, param("pageSize"), param("bookmark")},
+	},
+	{
+		Name:        "GetPrivateBallot",
+		Description: "Returns a ballot record from a private data collection.",
+		Parameters:  []APIParameter{param("collection"), param("electionID"), param("commitmentHash")},
+	},
+	{
+		Name:        "GetRawState",
+		Description: "Returns the raw value stored under any ledger key.",
+		Parameters:  []APIParameter{{"key", "Ledger key to read"}},
+		Roles:       []string{RoleAdministrator},
+	},
+	{
+		Name:        "GetReceipt",
+		Description: "Returns a vote receipt for the provided commitment.",
+		Parameters:  []APIParameter{param("commitmentHash")},
+	},
+	{
+		Name:        "GetReceiptByTxID",
+		Description: "Returns the vote receipt written by a transaction.",
+		Parameters:  []APIParameter{{"txID", "ID of the transaction that recorded the vote"}},
+	},
+	{
+		Name:        "GetReceiptProof",
+		Description: "Returns a vote receipt with its storage key and writing transaction.",
+		Parameters:  []APIParameter{param("commitmentHash")},
+	},
+	{
+		Name:        "GetReceipts",
+		Description: "Returns a vote receipt for every vote recorded under the provided commitment.",
+		Parameters:  []APIParameter{param("commitmentHash")},
+	},
+	{
+		Name:        "GetResults",
+		Description: "Returns the certified results of an election, or their latest amendment.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetResultsHistory",
+		Description: "Returns every version of an election's certified results, newest first.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetSignedReceipt",
+		Description: "Returns a vote receipt signed with the peer organization's attestation key.",
+		Parameters:  []APIParameter{param("commitmentHash")},
+	},
+	{
+		Name:        "GetStationCloses",
+		Description: "Returns the closing records of an election's polling stations, ordered by station ID.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetStatusHistory",
+		Description: "Returns every status change of an election, oldest first.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetSubjectStatus",
+		Description: "Reports whether a subject is registered and whether they have voted.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash")},
+	},
+	{
+		Name:        "GetTallySnapshot",
+		Description: "Returns the tally recorded when an election was closed.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetTurnout",
+		Description: "Returns an election's registered and voted counts and turnout percentage.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetUnregisteredVotes",
+		Description: "Returns the votes whose subject has no registration.",
+		Parameters:  []APIParameter{param("electionID")},
+		Roles:       []string{RoleAuditor},
+	},
+	{
+		Name:        "GetVoteCount",
+		Description: "Returns the number of subjects who have voted.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "GetVotesByElection",
+		Description: "Returns a page of an election's votes.",
+		Parameters:  []APIParameter{param("electionID"), param("pageSize"), param("bookmark")},
+		Roles:       []string{RoleAuditor},
+	},
+	{
+		Name:        "GetVotesByOption",
+		Description: "Returns a page of the votes for one option.",
+		Parameters:  []APIParameter{param("electionID"), {"optionID", "Option ID, or \"<contestID>/<optionID>\" in a multi-contest election"}, param("pageSize"), param("bookmark")},
+		Roles:       []string{RoleAuditor},
+	},
+	{
+		Name:        "GetVotesBySubject",
+		Description: "Returns the votes a subject cast.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash"), {"includeOption", "Whether to return the chosen options"}},
+		Roles:       []string{RoleAuditor},
+	},
+	{
+		Name:        "InitLedger",
+		Description: "Records the caller as the contract administrator.",
+	},
+	{
+		Name:        "InvalidateVote",
+		Description: "Invalidates a subject's votes so they may vote again.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash"), {"reason", "Why the vote is invalidated"}, param("officialID")},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "IsSubjectRegisteredElsewhere",
+		Description: "Returns the other elections a subject is registered for.",
+		Parameters:  []APIParameter{param("subjectHash"), {"excludeElectionID", "Election to leave out, or empty to list every election"}},
+	},
+	{
+		Name:        "ListAuditAnchors",
+		Description: "Returns the audit anchors within a time range, oldest first.",
+		Parameters:  []APIParameter{{"startTimestamp", "RFC3339 start of the range, or empty"}, {"endTimestamp", "RFC3339 end of the range, or empty"}},
+	},
+	{
+		Name:        "ListBallots",
+		Description: "Returns a page of an election's ballot commitments.",
+		Parameters:  []APIParameter{param("electionID"), param("pageSize"), param("bookmark")},
+	},
+	{
+		Name:        "ListChallenges",
+		Description: "Returns every challenge filed in an election.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "ListElections",
+		Description: "Returns a page of all elections ordered by election ID.",
+		Parameters:  []APIParameter{param("pageSize"), param("bookmark")},
+	},
+	{
+		Name:        "ListProvisionalVotes",
+		Description: "Returns the provisional votes of an election still awaiting review.",
+		Parameters:  []APIParameter{param("electionID")},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "LockElectionConfig",
+		Description: "Freezes a DRAFT election's configuration.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "MigrateKeys",
+		Description: "Moves an election's votes and ballots from legacy keys to composite keys, a page at a time.",
+		Parameters:  []APIParameter{param("electionID"), param("pageSize"), param("bookmark")},
+		Roles:       []string{RoleAdministrator},
+	},
+	{
+		Name:        "OpenElection",
+		Description: "Opens a DRAFT or REGISTRATION election for voting.",
+		Parameters:  []APIParameter{param("electionID")},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "OpenRegistration",
+		Description: "Starts the voter registration phase of a DRAFT election.",
+		Parameters:  []APIParameter{param("electionID")},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "PauseElection",
+		Description: "Temporarily stops an OPEN election accepting submissions.",
+		Parameters:  []APIParameter{param("electionID"), {"reason", "Why the election is paused"}},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "PreviewTally",
+		Description: "Computes the current tally of an election in any status.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "QueryBallots",
+		Description: "Runs a CouchDB selector query over ballot commitments.",
+		Parameters:  []APIParameter{{"queryString", "CouchDB selector query"}},
+	},
+	{
+		Name:        "ReconcileStationCloses",
+		Description: "Compares the station-reported ballot total with the ledger's ballot count.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "RegisterProxy",
+		Description: "Delegates a subject's vote to a proxy, who then also votes on their behalf.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash"), {"proxySubjectHash", "Hashed voter identifier of the proxy"}},
+	},
+	{
+		Name:        "RegisterSubject",
+		Description: "Registers a hashed voter for an election.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash")},
+	},
+	{
+		Name:        "RegisterSubjectWithStatus",
+		Description: "Registers a hashed voter and reports whether the registration is new.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash")},
+	},
+	{
+		Name:        "RegisterSubjects",
+		Description: "Registers a JSON array of hashed voters.",
+		Parameters:  []APIParameter{param("electionID"), {"subjectHashesJSON", "JSON array of hashed voter identifiers"}},
+	},
+	{
+		Name:        "RegisterWeightedSubject",
+		Description: "Registers a hashed voter with the weight their vote carries.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash"), {"weight", "Weight the subject's vote carries"}},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "RejectProvisionalVote",
+		Description: "Rejects a pending provisional vote.",
+		Parameters:  []APIParameter{param("electionID"), param("commitmentHash"), {"reason", "Why the vote is rejected"}, param("officialID")},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "ResolveChallenge",
+		Description: "Closes an OPEN challenge.",
+		Parameters:  []APIParameter{{"challengeID", "ID of the challenge"}, {"resolution", "UPHELD or DISMISSED"}},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "ResumeElection",
+		Description: "Reopens a PAUSED election.",
+		Parameters:  []APIParameter{param("electionID"), {"reason", "Why the election is resumed"}},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "RevealVote",
+		Description: "Reveals the option of a sealed vote.",
+		Parameters:  []APIParameter{param("electionID"), param("commitmentHash"), {"optionID", "ID of the option the vote was sealed for"}, {"salt", "Salt the commitment hash was computed with"}},
+	},
+	{
+		Name:        "SelectAuditSample",
+		Description: "Selects and records a reproducible random sample of ballot commitments from a seed.",
+		Parameters:  []APIParameter{param("electionID"), {"seed", "Public random seed"}, {"sampleSize", "Number of ballots to select"}},
+		Roles:       []string{RoleAuditor},
+	},
+	{
+		Name:        "SetAllowUnregistered",
+		Description: "Sets whether subjects may vote in a DRAFT election without registering first.",
+		Parameters:  []APIParameter{param("electionID"), {"allow", "Whether unregistered subjects may vote"}},
+	},
+	{
+		Name:        "SetAllowedCertifiers",
+		Description: "Replaces the MSP IDs allowed to certify results.",
+		Parameters:  []APIParameter{{"mspsJSON", "JSON array of MSP IDs"}},
+		Roles:       []string{RoleContractAdmin},
+	},
+	{
+		Name:        "SetAttestationKey",
+		Description: "Installs or rotates the receipt attestation key of the caller's organization.",
+	},
+	{
+		Name:        "SetBallotMetadataSchema",
+		Description: "Sets the metadata schema ballots submitted to a DRAFT election must satisfy.",
+		Parameters:  []APIParameter{param("electionID"), {"schemaJSON", "JSON object mapping metadata keys to {type, required}"}},
+	},
+	{
+		Name:        "SetElectionOptions",
+		Description: "Defines the valid option IDs for a DRAFT election.",
+		Parameters:  []APIParameter{param("electionID"), {"optionsJSON", "JSON array of option IDs"}},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "SetHashAlgorithm",
+		Description: "Sets the digest algorithm of a DRAFT election's commitment hashes.",
+		Parameters:  []APIParameter{param("electionID"), {"algorithm", "SHA-256, SHA-384, SHA-512 or empty"}},
+	},
+	{
+		Name:        "SetMaxClockSkew",
+		Description: "Sets how far a ballot's client timestamp may drift before it is flagged.",
+		Parameters:  []APIParameter{param("electionID"), {"seconds", "Allowed skew in seconds; zero restores the default"}},
+	},
+	{
+		Name:        "SetMaxTotalVotes",
+		Description: "Sets how many subjects may vote and how many ballots may be submitted.",
+		Parameters:  []APIParameter{param("electionID"), param("max")},
+	},
+	{
+		Name:        "SetMaxVotesPerOption",
+		Description: "Sets how many votes an option may receive before further votes are flagged.",
+		Parameters:  []APIParameter{param("electionID"), param("max")},
+	},
+	{
+		Name:        "SetMinVoteDelay",
+		Description: "Sets how long after registering a subject must wait before voting.",
+		Parameters:  []APIParameter{param("electionID"), param("seconds")},
+	},
+	{
+		Name:        "SetQuorumThreshold",
+		Description: "Sets the turnout percentage a DRAFT election needs for its results to be valid.",
+		Parameters:  []APIParameter{param("electionID"), {"threshold", "Turnout percentage from 0 to 100"}},
+	},
+	{
+		Name:        "SetRegistrationWindow",
+		Description: "Sets when subjects may register for a DRAFT election.",
+		Parameters:  []APIParameter{param("electionID"), {"opensAt", "RFC3339 time registration opens, or empty"}, {"closesAt", "RFC3339 time registration closes, or empty"}},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "SetRevealNotBefore",
+		Description: "Sets the time before which decryption shares may not be combined.",
+		Parameters:  []APIParameter{param("electionID"), {"revealNotBefore", "RFC3339 time, or empty"}},
+	},
+	{
+		Name:        "SetRevealTTL",
+		Description: "Sets how long a DRAFT election's sealed votes may stay unrevealed before they can be expired.",
+		Parameters:  []APIParameter{param("electionID"), param("seconds")},
+	},
+	{
+		Name:        "SetSealed",
+		Description: "Sets whether a DRAFT election's votes are sealed until revealed.",
+		Parameters:  []APIParameter{param("electionID"), {"sealed", "Whether votes are sealed"}},
+	},
+	{
+		Name:        "SetTrustees",
+		Description: "Sets the trustees of a DRAFT election and the identities that submit their decryption shares.",
+		Parameters:  []APIParameter{param("electionID"), {"trusteesJSON", "JSON object mapping trustee IDs to client identity IDs"}, {"threshold", "Number of trustee shares required to combine"}},
+	},
+	{
+		Name:        "SetWeighted",
+		Description: "Sets whether votes in a DRAFT election are weighted by the weights registered for their subjects.",
+		Parameters:  []APIParameter{param("electionID"), {"weighted", "Whether votes are weighted"}},
+	},
+	{
+		Name:        "SpoilBallot",
+		Description: "Marks a ballot commitment as spoiled.",
+		Parameters:  []APIParameter{param("electionID"), param("commitmentHash"), {"reason", "Why the ballot is spoiled"}},
+		Roles:       []string{RoleOfficial},
+	},
+	{
+		Name:        "SubjectExists",
+		Description: "Reports whether a subject is registered for an election.",
+		Parameters:  []APIParameter{param("electionID"), param("subjectHash")},
+	},
+	{
+		Name:        "SubmitBallotCommitment",
+		Description: "Records a ballot commitment.",
+		Parameters:  []APIParameter{param("electionID"), param("ballotID"), param("commitmentHash"), param("timestamp"), param("metadataJSON")},
+	},
+	{
+		Name:        "SubmitBallotCommitmentPrivate",
+		Description: "Records a ballot commitment with its metadata in a private data collection.",
+		Parameters:  []APIParameter{param("collection"), param("electionID"), param("ballotID"), param("commitmentHash"), param("timestamp")},
+	},
+	{
+		Name:        "SubmitChainedBallotCommitment",
+		Description: "Records a subject's ballot commitment chained to their previous one.",
+		Parameters:  []APIParameter{param("electionID"), param("ballotID"), param("commitmentHash"), param("subjectHash"), {"prevCommitmentHash", "Commitment hash of the subject's previous ballot, or empty for the first"}, param("timestamp"), param("metadataJSON")},
+	},
+	{
+		Name:        "SubmitDecryptionShare",
+		Description: "Anchors a trustee's decryption share for a CLOSED election.",
+		Parameters:  []APIParameter{param("electionID"), {"trusteeID", "ID of the trustee"}, {"shareJSON", "JSON decryption share"}},
+		Roles:       []string{RoleTrustee},
+	},
+	{
+		Name:        "SubmitSubjectBallotCommitment",
+		Description: "Records a ballot commitment together with the submitting subject.",
+		Parameters:  []APIParameter{param("electionID"), param("ballotID"), param("commitmentHash"), param("subjectHash"), param("timestamp"), param("metadataJSON")},
+	},
+	{
+		Name:        "TallyByStation",
+		Description: "Counts an election's votes from one polling station.",
+		Parameters:  []APIParameter{param("electionID"), param("stationID")},
+	},
+	{
+		Name:        "TallyRankedResults",
+		Description: "Runs an instant-runoff count.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "TallyResults",
+		Description: "Counts an election's votes by option, optionally storing the tally.",
+		Parameters:  []APIParameter{param("electionID"), {"contestID", "Contest to count, or empty for all"}, {"store", "Whether to store the tally on the ledger"}},
+	},
+	{
+		Name:        "TallyUncontestedResults",
+		Description: "Counts an election's votes, leaving out challenged ones.",
+		Parameters:  []APIParameter{param("electionID")},
+	},
+	{
+		Name:        "VerifyAuditInclusion",
+		Description: "Checks that a leaf is included under an anchored Merkle root.",
+		Parameters:  []APIParameter{param("merkleRoot"), {"leafHash", "Hex hash of the leaf"}, {"proofJSON", "JSON array of {hash, position} siblings"}},
+	},
+	{
+		Name:        "VerifyAuditInclusionBatch",
+		Description: "Checks many leaves against one anchored Merkle root.",
+		Parameters:  []APIParameter{param("merkleRoot"), {"leavesJSON", "JSON array of {leafHash, proof}"}},
+	},
+	{
+		Name:        "VerifyElectionConfig",
+		Description: "Checks an election's configuration against an expected hash.",
+		Parameters:  []APIParameter{param("electionID"), {"expectedHash", "Expected hex configuration hash"}},
+	},
+	{
+		Name:        "VoteExists",
+		Description: "Reports whether a vote is recorded under a commitment in an election.",
+		Parameters:  []APIParameter{param("electionID"), param("commitmentHash")},
+	},
+}
+
+// GetAPIInfo lists the contract's transactions with a description of each
+// argument and the roles that may call them, for frontends that would rather
+// not parse the Fabric contract metadata.
+func (c *BallotContract) GetAPIInfo() []APIFunction {
+	evaluate := map[string]bool{}
+	for _, name := range c.GetEvaluateTransactions() {
+		evaluate[name] = true
+	}
+
+	functions := make([]APIFunction, len(apiFunctions))
+	for i, function := range apiFunctions {
+		if function.Parameters == nil {
+			function.Parameters = []APIParameter{}
+		}
+		function.Evaluate = evaluate[function.Name]
+		functions[i] = function
+	}
+	return functions
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// TestAPIInfoMatchesContract fails if apiFunctions and the contract's
+// transactions have diverged: a transaction missing from the list, a listed
+// name that is not a transaction, or a differing number of arguments.
+func TestAPIInfoMatchesContract(t *testing.T) {
+	contextType := reflect.TypeOf((*contractapi.TransactionContextInterface)(nil)).Elem()
+	inherited := reflect.TypeOf(&contractapi.Contract{})
+	contract := reflect.TypeOf(&BallotContract{})
+
+	arguments := map[string]int{}
+	for i := 0; i < contract.NumMethod(); i++ {
+		method := contract.Method(i)
+		if _, ok := inherited.MethodByName(method.Name); ok || method.Name == "GetEvaluateTransactions" {
+			continue
+		}
+		count := 0
+		for j := 1; j < method.Type.NumIn(); j++ {
+			if method.Type.In(j) != contextType {
+				count++
+			}
+		}
+		arguments[method.Name] = count
+	}
+
+	listed := map[string]bool{}
+	for i, function := range apiFunctions {
+		if i > 0 && apiFunctions[i-1].Name >= function.Name {
+			t.Errorf("API info lists %s after %s; keep the list in alphabetical order", function.Name, apiFunctions[i-1].Name)
+		}
+		count, ok := arguments[function.Name]
+		if !ok {
+			t.Errorf("API info lists %s, which is not a transaction", function.Name)
+			continue
+		}
+		if count != len(function.Parameters) {
+			t.Errorf("API info lists %d parameters for %s, which takes %d", len(function.Parameters), function.Name, count)
+		}
+		listed[function.Name] = true
+	}
+
+	missing := []string{}
+	for name := range arguments {
+		if !listed[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		t.Errorf("API info does not list %v", missing)
+	}
+}
+
+func TestGetAPIInfoMarksEvaluateTransactions(t *testing.T) {
+	c := &BallotContract{}
+	evaluate := map[string]bool{}
+	for _, name := range c.GetEvaluateTransactions() {
+		evaluate[name] = true
+	}
+	for _, function := range c.GetAPIInfo() {
+		if function.Evaluate != evaluate[function.Name] {
+			t.Errorf("%s: Evaluate = %v", function.Name, function.Evaluate)
+		}
+		if function.Parameters == nil {
+			t.Errorf("%s: nil parameters", function.Name)
+		}
+	}
+}
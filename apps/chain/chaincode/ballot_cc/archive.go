@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// maxArchiveBatch bounds how many vote and ballot records one ArchiveElection
+// transaction deletes, keeping its write set within peer limits.
+const maxArchiveBatch = 500
+
+// archiveDigestKey holds the digest of the off-chain archive an election's
+// purge was confirmed with.
+func archiveDigestKey(electionID string) string {
+	return fmt.Sprintf("archive:%s", electionID)
+}
+
+// ArchiveResult reports the progress of ArchiveElection.
+type ArchiveResult struct {
+	Deleted  int  `json:"deleted"`
+	Complete bool `json:"complete"`
+}
+
+// ElectionArchivedEvent is the payload of ElectionArchived events.
+type ElectionArchivedEvent struct {
+	ElectionID    string `json:"electionId"`
+	ArchiveDigest string `json:"archiveDigest"`
+	Deleted       int    `json:"deleted"`
+	Complete      bool   `json:"complete"`
+	TxID          string `json:"txId"`
+}
+
+// ArchiveElection reclaims ledger state of a CERTIFIED election by deleting
+// its individual vote and ballot records together with their indexes, nonce
+// records, transaction index entries and has-voted markers. The election,
+// tally snapshot, certified results, audit anchors, event log and subject
+// registrations, weights and delegations are kept.
+// archiveDigest is the SHA-256 digest of the off-chain export of the records,
+// and confirmation must be "<electionID>:<archiveDigest>", guarding against
+// accidental purges. The first call records the digest; later calls must
+// repeat it. Only administrators may call it.
+// Each call deletes at most maxArchiveBatch records; call it again until
+// Complete is set, at which point the election moves to ARCHIVED. The deleted
+// records remain in the ledger's block history. Emits an "ElectionArchived"
+// event.
+func (c *BallotContract) ArchiveElection(
+	ctx contractapi.TransactionContextInterface,
+	electionID, archiveDigest, confirmation string,
+) (*ArchiveResult, error) {
+	if err := validateInputs(requireDigest("archive digest", archiveDigest, HashSHA256)); err != nil {
+		return nil, err
+	}
+	if err := requireMSP(ctx, adminMSPs, "administrator"); err != nil {
+		return nil, err
+	}
+	if confirmation != electionID+":"+archiveDigest {
+		return nil, codedErrorf(ErrCodeInvalidArgument, "confirmation must be the election ID and archive digest, joined by ':'")
+	}
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if election.Status != StatusCertified {
+		return nil, codedErrorf(ErrCodeInvalidStatus, "election %s is %s; only CERTIFIED elections can be archived", electionID, election.Status)
+	}
+
+	recorded, err := ctx.GetStub().GetState(archiveDigestKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	if recorded == nil {
+		if err := ctx.GetStub().PutState(archiveDigestKey(electionID), []byte(archiveDigest)); err != nil {
+			return nil, err
+		}
+	} else if string(recorded) != archiveDigest {
+		return nil, codedErrorf(ErrCodeInvalidArgument, "archival of election %s was started with archive digest %s", electionID, recorded)
+	}
+
+	deleted, err := archiveVotes(ctx, electionID, maxArchiveBatch)
+	if err != nil {
+		return nil, err
+	}
+	ballotsDeleted, err := archiveBallots(ctx, electionID, maxArchiveBatch-deleted)
+	if err != nil {
+		return nil, err
+	}
+	deleted += ballotsDeleted
+
+	result := &ArchiveResult{Deleted: deleted, Complete: deleted < maxArchiveBatch}
+	if result.Complete {
+		if err := transitionElection(ctx, electionID, StatusArchived, "", StatusCertified); err != nil {
+			return nil, err
+		}
+		if err := logEvent(ctx, electionID, EventElectionArchived); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := emitEvent(ctx, EventElectionArchived, ElectionArchivedEvent{
+		ElectionID:    electionID,
+		ArchiveDigest: archiveDigest,
+		Deleted:       result.Deleted,
+		Complete:      result.Complete,
+		TxID:          ctx.GetStub().GetTxID(),
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// archiveVotes deletes up to limit of an election's votes with their
+// commitment, subject, option, provisional and anomaly index entries, their
+// nonce and transaction index records and the has-voted markers of the
+// subjects they count for, and returns how many it deleted.
+func archiveVotes(ctx contractapi.TransactionContextInterface, electionID string, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{electionID})
+	if err != nil {
+		return 0, err
+	}
+	defer iterator.Close()
+
+	deleted := 0
+	for deleted < limit && iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return 0, err
+		}
+
+		var vote VoteCommitment
+		if err := unmarshalState(record.Value, &vote); err != nil {
+			return 0, err
+		}
+		commitmentIndexKey, err := ctx.GetStub().CreateCompositeKey(voteCommitmentIndex, []string{vote.CommitmentHash, electionID})
+		if err != nil {
+			return 0, err
+		}
+		subjectIndexKey, err := ctx.GetStub().CreateCompositeKey(voteSubjectIndex, []string{electionID, vote.voter(), vote.CommitmentHash})
+		if err != nil {
+			return 0, err
+		}
+		optionIndexKey, err := ctx.GetStub().CreateCompositeKey(voteOptionIndex, []string{electionID, vote.tallyOption(), vote.CommitmentHash})
+		if err != nil {
+			return 0, err
+		}
+		keys := []string{record.Key, commitmentIndexKey, subjectIndexKey, optionIndexKey, votedKey(electionID, vote.voter())}
+		// A proxy's transaction index entry and contest markers are its own
+		if vote.DelegatedFrom == "" {
+			if vote.TxID != "" {
+				keys = append(keys, voteTxIndexKey(vote.TxID))
+			}
+			if vote.ContestID != "" {
+				keys = append(keys, votedContestKey(electionID, vote.ContestID, vote.SubjectHash))
+			}
+		}
+		if vote.Nonce != "" {
+			keys = append(keys, voteNonceKey(electionID, vote.Nonce))
+		}
+		if vote.Provisional {
+			provisionalKey, err := ctx.GetStub().CreateCompositeKey(provisionalVoteIndex, []string{electionID, vote.CommitmentHash})
+			if err != nil {
+				return 0, err
+			}
+			keys = append(keys, provisionalKey)
+		}
+		if vote.Anomalous {
+			anomalyKey, err := ctx.GetStub().CreateCompositeKey(anomalousVoteIndex, []string{electionID, vote.CommitmentHash})
+			if err != nil {
+				return 0, err
+			}
+			keys = append(keys, anomalyKey)
+		}
+		for _, key := range keys {
+			if err := ctx.GetStub().DelState(key); err != nil {
+				return 0, err
+			}
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// archiveBallots deletes up to limit of an election's ballot commitments with
+// their index entries and returns how many it deleted.
+func archiveBallots(ctx contractapi.TransactionContextInterface, electionID string, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(ballotObjectType, []string{electionID})
+	if err != nil {
+		return 0, err
+	}
+	defer iterator.Close()
+
+	deleted := 0
+	for deleted < limit && iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return 0, err
+		}
+
+		var ballot BallotCommitment
+		if err := unmarshalState(record.Value, &ballot); err != nil {
+			return 0, err
+		}
+		keys := []string{record.Key}
+		if ballot.BallotID != "" {
+			keys = append(keys, ballotIDKey(electionID, ballot.BallotID))
+		}
+		if ballot.SubjectHash != "" {
+			subjectIndexKey, err := ctx.GetStub().CreateCompositeKey(
+				ballotSubjectIndex, []string{electionID, ballot.SubjectHash, ballot.CommitmentHash},
+			)
+			if err != nil {
+				return 0, err
+			}
+			keys = append(keys, subjectIndexKey, ballotChainKey(electionID, ballot.SubjectHash))
+		}
+		// The commitment index names the first election the hash was seen in
+		indexed, err := ctx.GetStub().GetState(ballotIndexKey(ballot.CommitmentHash))
+		if err != nil {
+			return 0, err
+		}
+		if string(indexed) == electionID {
+			keys = append(keys, ballotIndexKey(ballot.CommitmentHash))
+		}
+
+		for _, key := range keys {
+			if err := ctx.GetStub().DelState(key); err != nil {
+				return 0, err
+			}
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import "testing"
+
+func TestArchiveElectionRequiresAdministrator(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	env.certify("e1", 1)
+
+	env.setCaller("VoterOrgMSP")
+	digest := testHash(100)
+	err := env.invoke(func() error {
+		_, err := env.contract.ArchiveElection(env.ctx, "e1", digest, "e1:"+digest)
+		return err
+	})
+	wantCode(t, err, ErrCodeUnauthorized)
+}
+
+func TestArchiveElectionRejectsResultsHashConfirmation(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	env.certify("e1", 1)
+
+	var results *ElectionResult
+	env.mustInvoke(func() (err error) {
+		results, err = env.contract.GetResults(env.ctx, "e1")
+		return err
+	})
+	err := env.invoke(func() error {
+		_, err := env.contract.ArchiveElection(env.ctx, "e1", results.ResultsHash, results.ResultsHash)
+		return err
+	})
+	wantCode(t, err, ErrCodeInvalidArgument)
+}
+
+func TestArchiveElectionDeletesSecondaryKeys(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.mustInvoke(func() error {
+		return env.contract.CastVoteWithNonce(env.ctx, "e1", "subject1", testHash(1), "yes", "{}", "nonce1")
+	})
+	var receipt *VoteCommitment
+	env.mustInvoke(func() (err error) {
+		receipt, err = env.contract.GetReceipt(env.ctx, testHash(1))
+		return err
+	})
+	keys := []string{votedKey("e1", "subject1"), voteNonceKey("e1", "nonce1"), voteTxIndexKey(receipt.TxID)}
+	for _, key := range keys {
+		if value, err := env.stub.GetState(key); err != nil || value == nil {
+			t.Fatalf("key %s is not set before archiving (err %v)", key, err)
+		}
+	}
+	env.certify("e1", 1)
+
+	digest := testHash(100)
+	var result *ArchiveResult
+	env.mustInvoke(func() (err error) {
+		result, err = env.contract.ArchiveElection(env.ctx, "e1", digest, "e1:"+digest)
+		return err
+	})
+	if !result.Complete || result.Deleted != 1 {
+		t.Fatalf("got archive result %+v, want 1 deleted and complete", result)
+	}
+
+	for _, key := range keys {
+		value, err := env.stub.GetState(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != nil {
+			t.Errorf("key %s was not deleted", key)
+		}
+	}
+	recorded, err := env.stub.GetState(archiveDigestKey("e1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(recorded) != digest {
+		t.Fatalf("got recorded archive digest %q, want %s", recorded, digest)
+	}
+
+	var election *Election
+	env.mustInvoke(func() (err error) {
+		election, err = env.contract.GetElection(env.ctx, "e1")
+		return err
+	})
+	if election.Status != StatusArchived {
+		t.Fatalf("got status %s, want ARCHIVED", election.Status)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Receipt attestation keys.
+//
+// Each organization provisions an Ed25519 signing key for its own peers by
+// calling SetAttestationKey from one of its identities with the 32-byte
+// private key seed, base64 encoded, in the transient field "attestationKey".
+// The seed is kept in the organization's implicit private data collection
+// (only a hash reaches the ledger) and the public key is published under the
+// organization's MSP ID and its key ID, the hex SHA-256 of the public key, so
+// wallets can fetch it once via GetAttestationKey and verify receipts offline.
+// The records written depend only on the caller's MSP and the seed, so every
+// endorsing peer, whatever its organization, produces the same write set.
+//
+// To rotate, call SetAttestationKey again with a new seed. New receipts are
+// signed with the new key; earlier public keys stay published, marked with
+// the time they were retired, so receipts signed before the rotation still
+// verify. Ed25519 signatures are deterministic, so every endorsing peer of an
+// organization produces the same attestation.
+
+const attestationSeedKey = "attestation:seed"
+
+func attestationKeyKey(mspID, keyID string) string {
+	return fmt.Sprintf("attestkey:%s:%s", mspID, keyID)
+}
+
+// currentAttestationKeyKey holds the ID of an organization's active key.
+func currentAttestationKeyKey(mspID string) string {
+	return fmt.Sprintf("attestkey:%s", mspID)
+}
+
+// implicitCollection is the name of an organization's implicit private data
+// collection.
+func implicitCollection(mspID string) string {
+	return "_implicit_org_" + mspID
+}
+
+// AttestationKey is a published receipt attestation public key.
+type AttestationKey struct {
+	Record
+
+	KeyID       string `json:"keyId"`
+	MSPID       string `json:"mspId"`
+	PublicKey   string `json:"publicKey"`
+	ActivatedAt string `json:"activatedAt"`
+	RetiredAt   string `json:"retiredAt,omitempty"`
+}
+
+// SignedReceipt is a vote receipt with an organization's signature over
+// "electionID|commitmentHash|txID", where txID is the transaction that
+// recorded the vote.
+type SignedReceipt struct {
+	Commitment VoteCommitment `json:"commitment"`
+	TxID       string         `json:"txId"`
+	KeyID      string         `json:"keyId"`
+	MSPID      string         `json:"mspId"`
+	Signature  string         `json:"signature"`
+}
+
+func receiptSigningPayload(electionID, commitmentHash, txID string) []byte {
+	return []byte(electionID + "|" + commitmentHash + "|" + txID)
+}
+
+// SetAttestationKey installs or rotates the receipt attestation key of the
+// caller's organization and returns its key ID.
+func (c *BallotContract) SetAttestationKey(ctx contractapi.TransactionContextInterface) (string, error) {
+	mspID, err := clientMSP(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", err
+	}
+	seed, err := base64.StdEncoding.DecodeString(string(transient["attestationKey"]))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return "", codedErrorf(ErrCodeInvalidArgument, "transient attestationKey must be a base64 %d-byte Ed25519 seed", ed25519.SeedSize)
+	}
+	publicKey := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(publicKey)
+	keyID := hex.EncodeToString(sum[:])
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	timestamp := now.Format(time.RFC3339Nano)
+
+	// Retire the key being replaced
+	current, err := ctx.GetStub().GetState(currentAttestationKeyKey(mspID))
+	if err != nil {
+		return "", err
+	}
+	if current != nil && string(current) != keyID {
+		previous, err := getAttestationKey(ctx, mspID, string(current))
+		if err != nil {
+			return "", err
+		}
+		previous.RetiredAt = timestamp
+		if err := putAttestationKey(ctx, previous); err != nil {
+			return "", err
+		}
+	}
+
+	if err := ctx.GetStub().PutPrivateData(implicitCollection(mspID), attestationSeedKey, seed); err != nil {
+		return "", err
+	}
+	if err := putAttestationKey(ctx, &AttestationKey{
+		KeyID:       keyID,
+		MSPID:       mspID,
+		PublicKey:   base64.StdEncoding.EncodeToString(publicKey),
+		ActivatedAt: timestamp,
+	}); err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(currentAttestationKeyKey(mspID), []byte(keyID)); err != nil {
+		return "", err
+	}
+	return keyID, nil
+}
+
+func putAttestationKey(ctx contractapi.TransactionContextInterface, key *AttestationKey) error {
+	bytes, err := marshalState(key)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(attestationKeyKey(key.MSPID, key.KeyID), bytes)
+}
+
+func getAttestationKey(ctx contractapi.TransactionContextInterface, mspID, keyID string) (*AttestationKey, error) {
+	bytes, err := ctx.GetStub().GetState(attestationKeyKey(mspID, keyID))
+	if err != nil {
+		return nil, err
+	}
+	if bytes == nil {
+		return nil, codedErrorf(ErrCodeNotFound, "attestation key %s of %s not found", keyID, mspID)
+	}
+
+	var key AttestationKey
+	if err := unmarshalState(bytes, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAttestationKey returns an organization's published attestation public
+// key by key ID.
+func (c *BallotContract) GetAttestationKey(ctx contractapi.TransactionContextInterface, mspID, keyID string) (*AttestationKey, error) {
+	return getAttestationKey(ctx, mspID, keyID)
+}
+
+// GetSignedReceipt returns the vote receipt for a commitment signed with the
+// attestation key of the peer's organization. It should be evaluated rather
+// than submitted. The signature verifies against the public key MSPID
+// published under KeyID.
+func (c *BallotContract) GetSignedReceipt(ctx contractapi.TransactionContextInterface, commitmentHash string) (*SignedReceipt, error) {
+	_, vote, err := findVote(ctx, commitmentHash)
+	if err != nil {
+		return nil, err
+	}
+	txID, err := voteTxID(ctx, vote)
+	if err != nil {
+		return nil, err
+	}
+
+	peerMSP, err := shim.GetMSPID()
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := ctx.GetStub().GetState(currentAttestationKeyKey(peerMSP))
+	if err != nil {
+		return nil, err
+	}
+	seed, err := ctx.GetStub().GetPrivateData(implicitCollection(peerMSP), attestationSeedKey)
+	if err != nil {
+		return nil, err
+	}
+	if keyID == nil || len(seed) != ed25519.SeedSize {
+		return nil, codedErrorf(ErrCodeNotFound, "no attestation key provisioned for %s", peerMSP)
+	}
+
+	signature := ed25519.Sign(ed25519.NewKeyFromSeed(seed), receiptSigningPayload(vote.ElectionID, vote.CommitmentHash, txID))
+	return &SignedReceipt{
+		Commitment: *vote,
+		TxID:       txID,
+		KeyID:      string(keyID),
+		MSPID:      peerMSP,
+		Signature:  base64.StdEncoding.EncodeToString(signature),
+	}, nil
+}
+
+// voteTxID returns the ID of the transaction that recorded a vote. Votes
+// recorded before the ID was stored on the vote fall back to the subject's
+// voted marker.
+func voteTxID(ctx contractapi.TransactionContextInterface, vote *VoteCommitment) (string, error) {
+	if vote.TxID != "" {
+		return vote.TxID, nil
+	}
+
+	markerKey := votedKey(vote.ElectionID, vote.SubjectHash)
+	if vote.ContestID != "" {
+		markerKey = votedContestKey(vote.ElectionID, vote.ContestID, vote.SubjectHash)
+	}
+	marker, err := ctx.GetStub().GetState(markerKey)
+	if err != nil {
+		return "", err
+	}
+	if marker == nil || string(marker) == "voted" {
+		return "", codedErrorf(ErrCodeNotFound, "transaction ID of vote %s was not recorded", vote.CommitmentHash)
+	}
+	return string(marker), nil
+}
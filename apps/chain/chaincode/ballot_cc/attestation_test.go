@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+)
+
+// transientStub supplies the transient data MockStub does not.
+type transientStub struct {
+	*shimtest.MockStub
+	transient map[string][]byte
+}
+
+func (s *transientStub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+// withAttestationSeed makes later transactions carry seed as the transient
+// attestationKey.
+func (env *testEnv) withAttestationSeed(seed []byte) {
+	env.ctx.SetStub(&transientStub{
+		MockStub:  env.stub,
+		transient: map[string][]byte{"attestationKey": []byte(base64.StdEncoding.EncodeToString(seed))},
+	})
+}
+
+func TestSetAttestationKeyKeyedByCallerMSP(t *testing.T) {
+	// Endorsed by a peer of another organization
+	t.Setenv("CORE_PEER_LOCALMSPID", "AuditorMSP")
+	env := newTestEnv(t)
+	env.withAttestationSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+
+	var keyID string
+	env.mustInvoke(func() (err error) {
+		keyID, err = env.contract.SetAttestationKey(env.ctx)
+		return err
+	})
+	current, err := env.stub.GetState(currentAttestationKeyKey("ElectionCommissionMSP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current) != keyID {
+		t.Fatalf("got current key %q for the caller's MSP, want %s", current, keyID)
+	}
+
+	var key *AttestationKey
+	env.mustInvoke(func() (err error) {
+		key, err = env.contract.GetAttestationKey(env.ctx, "ElectionCommissionMSP", keyID)
+		return err
+	})
+	if key.MSPID != "ElectionCommissionMSP" {
+		t.Fatalf("got key for %s, want ElectionCommissionMSP", key.MSPID)
+	}
+	err = env.invoke(func() error {
+		_, err := env.contract.GetAttestationKey(env.ctx, "AuditorMSP", keyID)
+		return err
+	})
+	wantCode(t, err, ErrCodeNotFound)
+}
+
+func TestSetAttestationKeyRejectsInvalidSeed(t *testing.T) {
+	env := newTestEnv(t)
+	env.withAttestationSeed([]byte("short"))
+
+	err := env.invoke(func() error {
+		_, err := env.contract.SetAttestationKey(env.ctx)
+		return err
+	})
+	wantCode(t, err, ErrCodeInvalidArgument)
+}
+
+func TestGetSignedReceiptVerifies(t *testing.T) {
+	t.Setenv("CORE_PEER_LOCALMSPID", "ElectionCommissionMSP")
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	env.withAttestationSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	env.mustInvoke(func() error {
+		_, err := env.contract.SetAttestationKey(env.ctx)
+		return err
+	})
+
+	var receipt *SignedReceipt
+	env.mustInvoke(func() (err error) {
+		receipt, err = env.contract.GetSignedReceipt(env.ctx, testHash(1))
+		return err
+	})
+	var key *AttestationKey
+	env.mustInvoke(func() (err error) {
+		key, err = env.contract.GetAttestationKey(env.ctx, receipt.MSPID, receipt.KeyID)
+		return err
+	})
+	publicKey, err := base64.StdEncoding.DecodeString(key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(receipt.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(publicKey, receiptSigningPayload("e1", testHash(1), receipt.TxID), signature) {
+		t.Fatal("receipt signature does not verify against the published key")
+	}
+}
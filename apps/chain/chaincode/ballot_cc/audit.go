@@ -0,0 +1,547 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Positions of a sibling hash relative to the running hash in a Merkle proof.
+const (
+	ProofLeft  = "left"
+	ProofRight = "right"
+)
+
+// MerkleProofStep is one sibling on the path from a leaf to the Merkle root.
+type MerkleProofStep struct {
+	Hash     string `json:"hash"`
+	Position string `json:"position"`
+}
+
+func auditKey(merkleRoot string) string {
+	return fmt.Sprintf("audit:%s", merkleRoot)
+}
+
+// latestAuditRootKey points at the root most recently anchored for an election.
+func latestAuditRootKey(electionID string) string {
+	return fmt.Sprintf("latestAuditRoot:%s", electionID)
+}
+
+// hashPair returns SHA-256(left || right) over the raw bytes of two hex hashes.
+func hashPair(left, right []byte) []byte {
+	sum := sha256.Sum256(append(append([]byte{}, left...), right...))
+	return sum[:]
+}
+
+// computeMerkleRoot folds a proof path into a leaf hash and returns the
+// resulting root as lowercase hex.
+func computeMerkleRoot(leafHash string, proof []MerkleProofStep) (string, error) {
+	current, err := hex.DecodeString(leafHash)
+	if err != nil {
+		return "", fmt.Errorf("invalid leaf hash: %w", err)
+	}
+
+	for i, step := range proof {
+		sibling, err := hex.DecodeString(step.Hash)
+		if err != nil {
+			return "", fmt.Errorf("invalid proof hash at step %d: %w", i, err)
+		}
+		switch step.Position {
+		case ProofLeft:
+			current = hashPair(sibling, current)
+		case ProofRight:
+			current = hashPair(current, sibling)
+		default:
+			return "", fmt.Errorf("invalid proof position %q at step %d", step.Position, i)
+		}
+	}
+
+	return hex.EncodeToString(current), nil
+}
+
+// ballotMerkleRoot builds a Merkle tree over the given leaf hashes in order,
+// hashing pairs with hashPair and pairing the last node of an odd-sized level
+// with itself, and returns the root as lowercase hex.
+func ballotMerkleRoot(leafHashes []string) (string, error) {
+	level := make([][]byte, 0, len(leafHashes))
+	for _, leaf := range leafHashes {
+		decoded, err := hex.DecodeString(leaf)
+		if err != nil {
+			return "", fmt.Errorf("invalid leaf hash %s: %w", leaf, err)
+		}
+		level = append(level, decoded)
+	}
+
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([][]byte, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			next = append(next, hashPair(level[i], level[i+1]))
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0]), nil
+}
+
+// ComputeBallotMerkleRoot rebuilds, from ledger state, the Merkle root over
+// every ballot commitment recorded for an election, spoiled ones included.
+// The leaves are the raw bytes of the commitment hashes sorted by their
+// lowercase hex; parents are hashed as for VerifyAuditInclusion, and the last
+// node of an odd-sized level is paired with itself.
+func (c *BallotContract) ComputeBallotMerkleRoot(ctx contractapi.TransactionContextInterface, electionID string) (string, error) {
+	if err := requireKeyID("election ID", electionID); err != nil {
+		return "", err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(ballotObjectType, []string{electionID})
+	if err != nil {
+		return "", err
+	}
+	defer iterator.Close()
+
+	hashes := []string{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return "", err
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(record.Key)
+		if err != nil {
+			return "", err
+		}
+		if len(parts) != 2 {
+			continue
+		}
+		hashes = append(hashes, strings.ToLower(parts[1]))
+	}
+	if len(hashes) == 0 {
+		return "", fmt.Errorf("no ballot commitments recorded for election %s", electionID)
+	}
+
+	sort.Strings(hashes)
+	return ballotMerkleRoot(hashes)
+}
+
+// AnchorVerifiedAuditLogs anchors a Merkle root like AnchorAuditLogs, but
+// first checks that it equals the root ComputeBallotMerkleRoot rebuilds from
+// the election's ballot commitments, so the anchor does not rest on the
+// submitter's computation alone.
+func (c *BallotContract) AnchorVerifiedAuditLogs(
+	ctx contractapi.TransactionContextInterface,
+	electionID, merkleRoot, previousRoot, timestamp string,
+	batchSize int,
+	metadataJSON string,
+) error {
+	computed, err := c.ComputeBallotMerkleRoot(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(computed, merkleRoot) {
+		return fmt.Errorf("merkle root %s does not match root %s computed from ledger ballots", merkleRoot, computed)
+	}
+	return c.AnchorAuditLogs(ctx, electionID, merkleRoot, previousRoot, timestamp, batchSize, metadataJSON)
+}
+
+// VerifyAuditInclusion checks that leafHash is included under an anchored
+// Merkle root. proofJSON is an ordered array of {hash, position} siblings from
+// the leaf upwards, where position is "left" or "right" of the running hash.
+// Parent nodes are SHA-256 over the concatenated raw bytes of their children.
+func (c *BallotContract) VerifyAuditInclusion(
+	ctx contractapi.TransactionContextInterface,
+	merkleRoot, leafHash, proofJSON string,
+) (bool, error) {
+	var proof []MerkleProofStep
+	if err := json.Unmarshal([]byte(proofJSON), &proof); err != nil {
+		return false, err
+	}
+
+	anchored, err := ctx.GetStub().GetState(auditKey(merkleRoot))
+	if err != nil {
+		return false, err
+	}
+	if anchored == nil {
+		return false, fmt.Errorf("merkle root not anchored")
+	}
+
+	computed, err := computeMerkleRoot(leafHash, proof)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.EqualFold(computed, merkleRoot), nil
+}
+
+// AuditLeaf is one leaf to check in VerifyAuditInclusionBatch.
+type AuditLeaf struct {
+	LeafHash string            `json:"leafHash"`
+	Proof    []MerkleProofStep `json:"proof"`
+}
+
+// AuditInclusionBatchResult reports VerifyAuditInclusionBatch: Results[i]
+// tells whether leaf i is included, and OverallValid whether all are.
+type AuditInclusionBatchResult struct {
+	Results      []bool `json:"results"`
+	OverallValid bool   `json:"overallValid"`
+}
+
+// VerifyAuditInclusionBatch checks many leaves against one anchored Merkle
+// root, as VerifyAuditInclusion does for one. leavesJSON is an array of
+// {leafHash, proof}. Every leaf is checked; a leaf whose hash or proof is
+// malformed is reported as not included rather than failing the call.
+func (c *BallotContract) VerifyAuditInclusionBatch(
+	ctx contractapi.TransactionContextInterface,
+	merkleRoot, leavesJSON string,
+) (*AuditInclusionBatchResult, error) {
+	if err := maxLength("leaves", leavesJSON, maxBatchJSONLength); err != nil {
+		return nil, err
+	}
+
+	var leaves []AuditLeaf
+	if err := json.Unmarshal([]byte(leavesJSON), &leaves); err != nil {
+		return nil, err
+	}
+	if len(leaves) == 0 {
+		return nil, fmt.Errorf("at least one leaf is required")
+	}
+
+	anchored, err := ctx.GetStub().GetState(auditKey(merkleRoot))
+	if err != nil {
+		return nil, err
+	}
+	if anchored == nil {
+		return nil, fmt.Errorf("merkle root not anchored")
+	}
+
+	result := &AuditInclusionBatchResult{Results: make([]bool, len(leaves)), OverallValid: true}
+	for i, leaf := range leaves {
+		computed, err := computeMerkleRoot(leaf.LeafHash, leaf.Proof)
+		result.Results[i] = err == nil && strings.EqualFold(computed, merkleRoot)
+		if !result.Results[i] {
+			result.OverallValid = false
+		}
+	}
+	return result, nil
+}
+
+// ListAuditAnchors returns the anchored audit log entries whose timestamp lies
+// within [startTimestamp, endTimestamp], sorted oldest first. Both bounds are
+// RFC3339; an empty bound leaves that side of the range open. Entries whose
+// stored timestamp cannot be parsed are omitted.
+func (c *BallotContract) ListAuditAnchors(
+	ctx contractapi.TransactionContextInterface,
+	startTimestamp, endTimestamp string,
+) ([]AuditLogEntry, error) {
+	var start, end time.Time
+	var err error
+	if startTimestamp != "" {
+		if start, err = parseTimestamp(startTimestamp); err != nil {
+			return nil, err
+		}
+	}
+	if endTimestamp != "" {
+		if end, err = parseTimestamp(endTimestamp); err != nil {
+			return nil, err
+		}
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return nil, fmt.Errorf("end timestamp is before start timestamp")
+	}
+
+	iterator, err := ctx.GetStub().GetStateByRange("audit:", "audit;")
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	type anchor struct {
+		entry AuditLogEntry
+		at    time.Time
+	}
+	anchors := []anchor{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var entry AuditLogEntry
+		if err := unmarshalState(record.Value, &entry); err != nil {
+			return nil, err
+		}
+		at, err := parseTimestamp(entry.Timestamp)
+		if err != nil {
+			continue
+		}
+		if (!start.IsZero() && at.Before(start)) || (!end.IsZero() && at.After(end)) {
+			continue
+		}
+		anchors = append(anchors, anchor{entry: entry, at: at})
+	}
+
+	sort.SliceStable(anchors, func(i, j int) bool {
+		if anchors[i].at.Equal(anchors[j].at) {
+			return anchors[i].entry.MerkleRoot < anchors[j].entry.MerkleRoot
+		}
+		return anchors[i].at.Before(anchors[j].at)
+	})
+
+	entries := make([]AuditLogEntry, 0, len(anchors))
+	for _, a := range anchors {
+		entries = append(entries, a.entry)
+	}
+	return entries, nil
+}
+
+// ElectionAuditSummary cross-checks an election's certified results against
+// the votes on the ledger and its anchored audit log.
+type ElectionAuditSummary struct {
+	ElectionID          string         `json:"electionId"`
+	Status              ElectionStatus `json:"status"`
+	Certified           bool           `json:"certified"`
+	ResultsHash         string         `json:"resultsHash,omitempty"`
+	CertifiedTotalVotes int            `json:"certifiedTotalVotes"`
+	ComputedTotalVotes  int            `json:"computedTotalVotes"`
+	SnapshotTotalVotes  int            `json:"snapshotTotalVotes,omitempty"`
+	SnapshotResultsHash string         `json:"snapshotResultsHash,omitempty"`
+	LatestAuditRoot     string         `json:"latestAuditRoot,omitempty"`
+	Consistent          bool           `json:"consistent"`
+	Inconsistencies     []string       `json:"inconsistencies"`
+}
+
+// GetElectionAuditSummary returns an election's certified results hash and
+// vote total, the vote total computed from the ledger, its tally snapshot and
+// its latest anchored audit root, listing any figures that disagree.
+func (c *BallotContract) GetElectionAuditSummary(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*ElectionAuditSummary, error) {
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &ElectionAuditSummary{
+		ElectionID:      electionID,
+		Status:          election.Status,
+		Inconsistencies: []string{},
+	}
+
+	tally, err := computeTally(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	summary.ComputedTotalVotes = tally.TotalVotes
+
+	latestRoot, err := ctx.GetStub().GetState(latestAuditRootKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	summary.LatestAuditRoot = string(latestRoot)
+
+	snapshotBytes, err := ctx.GetStub().GetState(tallySnapshotKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	var snapshot *TallySnapshot
+	if snapshotBytes != nil {
+		snapshot = &TallySnapshot{}
+		if err := unmarshalState(snapshotBytes, snapshot); err != nil {
+			return nil, err
+		}
+		summary.SnapshotTotalVotes = snapshot.TotalVotes
+		// An archived election's votes are purged, so its snapshot stands in
+		// for the computed count
+		if election.Status == StatusArchived {
+			summary.ComputedTotalVotes = snapshot.TotalVotes
+		}
+		if summary.SnapshotResultsHash, err = canonicalResultsHash(&snapshot.Tally); err != nil {
+			return nil, err
+		}
+		if snapshot.TotalVotes != summary.ComputedTotalVotes {
+			summary.Inconsistencies = append(summary.Inconsistencies, fmt.Sprintf(
+				"computed vote count %d differs from tally snapshot count %d", summary.ComputedTotalVotes, snapshot.TotalVotes))
+		}
+	}
+
+	resultsBytes, err := ctx.GetStub().GetState(resultsKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	if resultsBytes != nil {
+		var results ElectionResult
+		if err := unmarshalState(resultsBytes, &results); err != nil {
+			return nil, err
+		}
+		summary.Certified = true
+		summary.ResultsHash = results.ResultsHash
+		summary.CertifiedTotalVotes = results.TotalVotes
+
+		if results.TotalVotes != summary.ComputedTotalVotes {
+			summary.Inconsistencies = append(summary.Inconsistencies, fmt.Sprintf(
+				"certified vote count %d differs from computed count %d", results.TotalVotes, summary.ComputedTotalVotes))
+		}
+		// Amended results are expected to differ from the snapshot.
+		if snapshot != nil && !results.Amended && !strings.EqualFold(results.ResultsHash, summary.SnapshotResultsHash) {
+			summary.Inconsistencies = append(summary.Inconsistencies, fmt.Sprintf(
+				"certified results hash %s differs from tally snapshot hash %s", results.ResultsHash, summary.SnapshotResultsHash))
+		}
+	} else if election.Status == StatusCertified || election.Status == StatusArchived {
+		summary.Inconsistencies = append(summary.Inconsistencies, fmt.Sprintf("election is %s but has no results record", election.Status))
+	}
+
+	summary.Consistent = len(summary.Inconsistencies) == 0
+	return summary, nil
+}
+
+// ConsistencyReport lists the invariants of an election's ledger state that
+// CheckElectionConsistency found violated.
+type ConsistencyReport struct {
+	ElectionID    string   `json:"electionId"`
+	VoteCounter   int      `json:"voteCounter"`
+	ScannedVoters int      `json:"scannedVoters"`
+	ScannedVotes  int      `json:"scannedVotes"`
+	Consistent    bool     `json:"consistent"`
+	Violations    []string `json:"violations"`
+}
+
+// CheckElectionConsistency scans an election's votes and checks that the
+// maintained vote counter equals the number of subjects with a valid vote,
+// that every vote names an option of the election (or its contest), and,
+// unless the election allows unregistered subjects, that every vote's subject
+// is registered. It is meant to be run before certifying. Archived elections
+// cannot be checked; their votes have been purged.
+func (c *BallotContract) CheckElectionConsistency(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*ConsistencyReport, error) {
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if election.Status == StatusArchived {
+		return nil, fmt.Errorf("election %s is archived; its votes have been purged", electionID)
+	}
+
+	counter, err := readVoteCount(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	report := &ConsistencyReport{ElectionID: electionID, VoteCounter: counter, Violations: []string{}}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{electionID})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	voters := map[string]bool{}
+	registered := map[string]bool{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var vote VoteCommitment
+		if err := unmarshalState(record.Value, &vote); err != nil {
+			return nil, err
+		}
+		report.ScannedVotes++
+		if vote.Sealed && !vote.Revealed {
+			if err := election.checkSealedVote(&vote); err != nil {
+				report.Violations = append(report.Violations, fmt.Sprintf("vote %s: %v", vote.CommitmentHash, err))
+			}
+		} else if err := election.checkVoteOption(&vote); err != nil {
+			report.Violations = append(report.Violations, fmt.Sprintf("vote %s: %v", vote.CommitmentHash, err))
+		}
+
+		if !election.AllowUnregistered && !vote.Provisional {
+			known, checked := registered[vote.SubjectHash]
+			if !checked {
+				subject, err := ctx.GetStub().GetState(subjectKey(electionID, vote.SubjectHash))
+				if err != nil {
+					return nil, err
+				}
+				known = subject != nil
+				registered[vote.SubjectHash] = known
+			}
+			if !known {
+				report.Violations = append(report.Violations, fmt.Sprintf("vote %s references unregistered subject %s", vote.CommitmentHash, vote.SubjectHash))
+			}
+		}
+		if !vote.Invalidated {
+			voters[vote.voter()] = true
+		}
+	}
+
+	report.ScannedVoters = len(voters)
+	if report.ScannedVoters != counter {
+		report.Violations = append(report.Violations, fmt.Sprintf(
+			"vote counter %d differs from %d subjects with a recorded vote", counter, report.ScannedVoters))
+	}
+
+	report.Consistent = len(report.Violations) == 0
+	return report, nil
+}
+
+// GetUnregisteredVotes returns the votes of an election whose subject has no
+// registration, such as votes cast before CastVote required one, so auditors
+// can quantify them. Provisional votes, which need no registration, are left
+// out. It returns an empty list when every vote's subject is registered. Only
+// callers from an auditor organization may query it.
+func (c *BallotContract) GetUnregisteredVotes(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) ([]VoteCommitment, error) {
+	if err := requireMSP(ctx, auditorMSPs, "auditor"); err != nil {
+		return nil, err
+	}
+	if _, err := getElection(ctx, electionID); err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{electionID})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	votes := []VoteCommitment{}
+	registered := map[string]bool{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var vote VoteCommitment
+		if err := unmarshalState(record.Value, &vote); err != nil {
+			return nil, err
+		}
+		if vote.Provisional {
+			continue
+		}
+		known, checked := registered[vote.SubjectHash]
+		if !checked {
+			subject, err := ctx.GetStub().GetState(subjectKey(electionID, vote.SubjectHash))
+			if err != nil {
+				return nil, err
+			}
+			known = subject != nil
+			registered[vote.SubjectHash] = known
+		}
+		if !known {
+			votes = append(votes, vote)
+		}
+	}
+
+	return votes, nil
+}
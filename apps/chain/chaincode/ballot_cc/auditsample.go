@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const auditSampleObjectType = "auditsample"
+
+// AuditSample is a reproducible random sample of an election's ballot
+// commitments for a risk-limiting audit. Anyone can recompute it from the
+// seed: take the hex HMAC-SHA256 of each unspoiled ballot's commitment hash
+// keyed with the seed, sort ascending, and keep the first SampleSize.
+type AuditSample struct {
+	Record
+
+	ElectionID       string   `json:"electionId"`
+	Seed             string   `json:"seed"`
+	SampleSize       int      `json:"sampleSize"`
+	Population       int      `json:"population"`
+	CommitmentHashes []string `json:"commitmentHashes"`
+	SelectedAt       string   `json:"selectedAt"`
+	TxID             string   `json:"txId"`
+}
+
+// sampleDigest ranks a commitment hash for sampling under seed.
+func sampleDigest(seed, commitmentHash string) string {
+	mac := hmac.New(sha256.New, []byte(seed))
+	mac.Write([]byte(commitmentHash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SelectAuditSample selects sampleSize of a CLOSED or CERTIFIED election's
+// unspoiled ballot commitments using seed, as described on AuditSample, and
+// records the sample. The whole population is selected when it is smaller
+// than sampleSize. Each seed may be used once per election; fetch an earlier
+// sample with GetAuditSample. Only callers from an auditor organization may
+// call it.
+func (c *BallotContract) SelectAuditSample(
+	ctx contractapi.TransactionContextInterface,
+	electionID, seed string,
+	sampleSize int,
+) (*AuditSample, error) {
+	if err := validateInputs(
+		requireKeyID("election ID", electionID),
+		requireID("seed", seed),
+	); err != nil {
+		return nil, err
+	}
+	if sampleSize <= 0 {
+		return nil, codedErrorf(ErrCodeInvalidArgument, "sample size must be positive")
+	}
+	if err := requireMSP(ctx, auditorMSPs, "auditor"); err != nil {
+		return nil, err
+	}
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	switch election.Status {
+	case StatusClosed, StatusCertified:
+	default:
+		return nil, codedErrorf(ErrCodeInvalidStatus, "audit samples cannot be selected while election %s is %s", electionID, election.Status)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(auditSampleObjectType, []string{electionID, seed})
+	if err != nil {
+		return nil, err
+	}
+	exists, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if exists != nil {
+		return nil, codedErrorf(ErrCodeAlreadyExists, "an audit sample with this seed already exists for election %s", electionID)
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(ballotObjectType, []string{electionID})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	type ranked struct {
+		digest, commitmentHash string
+	}
+	population := []ranked{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var ballot BallotCommitment
+		if err := unmarshalState(record.Value, &ballot); err != nil {
+			return nil, err
+		}
+		if ballot.Spoiled {
+			continue
+		}
+		population = append(population, ranked{sampleDigest(seed, ballot.CommitmentHash), ballot.CommitmentHash})
+	}
+	sort.Slice(population, func(i, j int) bool {
+		return population[i].digest < population[j].digest
+	})
+
+	selected := population
+	if len(selected) > sampleSize {
+		selected = selected[:sampleSize]
+	}
+	hashes := make([]string, len(selected))
+	for i, entry := range selected {
+		hashes[i] = entry.commitmentHash
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sample := &AuditSample{
+		ElectionID:       electionID,
+		Seed:             seed,
+		SampleSize:       sampleSize,
+		Population:       len(population),
+		CommitmentHashes: hashes,
+		SelectedAt:       now.Format(time.RFC3339Nano),
+		TxID:             ctx.GetStub().GetTxID(),
+	}
+	bytes, err := marshalState(sample)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.GetStub().PutState(key, bytes); err != nil {
+		return nil, err
+	}
+	return sample, nil
+}
+
+// GetAuditSample returns the audit sample selected for an election with seed.
+func (c *BallotContract) GetAuditSample(
+	ctx contractapi.TransactionContextInterface,
+	electionID, seed string,
+) (*AuditSample, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(auditSampleObjectType, []string{electionID, seed})
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if bytes == nil {
+		return nil, codedErrorf(ErrCodeNotFound, "no audit sample with this seed for election %s", electionID)
+	}
+
+	var sample AuditSample
+	if err := unmarshalState(bytes, &sample); err != nil {
+		return nil, err
+	}
+	return &sample, nil
+}
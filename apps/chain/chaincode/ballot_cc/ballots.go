@@ -0,0 +1,699 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// BallotPage is a page of ballot commitments returned by paginated queries.
+type BallotPage struct {
+	Ballots      []BallotCommitment `json:"ballots"`
+	Bookmark     string             `json:"bookmark"`
+	FetchedCount int                `json:"fetchedCount"`
+}
+
+// BallotHistoryEntry is one modification of a ballot commitment key.
+type BallotHistoryEntry struct {
+	TxID      string            `json:"txId"`
+	Timestamp string            `json:"timestamp"`
+	IsDeleted bool              `json:"isDeleted"`
+	Ballot    *BallotCommitment `json:"ballot,omitempty"`
+}
+
+// ballotKey returns the composite key a ballot commitment is stored under.
+func ballotKey(ctx contractapi.TransactionContextInterface, electionID, commitmentHash string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(ballotObjectType, []string{electionID, commitmentHash})
+}
+
+// ballotIndexKey maps a commitment hash to the election it was first submitted
+// in, so a ballot can be fetched by hash without scanning every election.
+func ballotIndexKey(commitmentHash string) string {
+	return fmt.Sprintf("ballotidx:%s", commitmentHash)
+}
+
+// ballotIDKey maps a ballot ID to the commitment hash recorded for it.
+func ballotIDKey(electionID, ballotID string) string {
+	return fmt.Sprintf("ballotid:%s:%s", electionID, ballotID)
+}
+
+// requireBallotIDUnused returns an error if the ballot already has a commitment.
+func requireBallotIDUnused(ctx contractapi.TransactionContextInterface, electionID, ballotID string) error {
+	existing, err := ctx.GetStub().GetState(ballotIDKey(electionID, ballotID))
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return codedErrorf(ErrCodeBallotConflict, "ballot %s already has a different commitment", ballotID)
+	}
+	return nil
+}
+
+// ballotReceipt is the part of a BallotCommitment covered by its receipt hash.
+// Field order is part of the hash definition.
+type ballotReceipt struct {
+	ElectionID     string         `json:"electionId"`
+	BallotID       string         `json:"ballotId"`
+	CommitmentHash string         `json:"commitmentHash"`
+	SubjectHash    string         `json:"subjectHash"`
+	Timestamp      string         `json:"timestamp"`
+	Metadata       map[string]any `json:"metadata"`
+}
+
+// receiptHash returns the canonical receipt hash of a ballot; see
+// GetBallotReceiptHash.
+func (b *BallotCommitment) receiptHash() (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(ballotReceipt{
+		ElectionID:     b.ElectionID,
+		BallotID:       b.BallotID,
+		CommitmentHash: b.CommitmentHash,
+		SubjectHash:    b.SubjectHash,
+		Timestamp:      b.Timestamp,
+		Metadata:       b.Metadata,
+	}); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// GetBallotReceiptHash returns the canonical hash of the ballot commitment
+// stored for a ballot ID, so a voter's app can compare it with the value it
+// computed when submitting. The hash is the hex SHA-256 of compact JSON with
+// exactly these fields in this order:
+//
+//	{"electionId":…,"ballotId":…,"commitmentHash":…,"subjectHash":…,"timestamp":…,"metadata":…}
+//
+// subjectHash is "" for ballots not tied to a subject, metadata is the
+// submitted metadata object with keys sorted (null for private ballots, whose
+// metadata is not public), and HTML characters are not escaped. Fields set by
+// the ledger (txId, recordedAt, clockSkewed, spoiled and the like) are
+// excluded.
+func (c *BallotContract) GetBallotReceiptHash(
+	ctx contractapi.TransactionContextInterface,
+	electionID, ballotID string,
+) (string, error) {
+	ballot, err := getBallotByID(ctx, electionID, ballotID)
+	if err != nil {
+		return "", err
+	}
+	return ballot.receiptHash()
+}
+
+// getBallotByID reads the ballot commitment recorded for a ballot ID through
+// the ballot ID index.
+func getBallotByID(ctx contractapi.TransactionContextInterface, electionID, ballotID string) (*BallotCommitment, error) {
+	commitmentHash, err := ctx.GetStub().GetState(ballotIDKey(electionID, ballotID))
+	if err != nil {
+		return nil, err
+	}
+	if commitmentHash == nil {
+		return nil, codedErrorf(ErrCodeNotFound, "no commitment recorded for ballot %s", ballotID)
+	}
+	return getBallot(ctx, electionID, string(commitmentHash))
+}
+
+// sameSubmission reports whether a stored ballot matches a resubmission of
+// the same commitment field for field.
+func (b *BallotCommitment) sameSubmission(ballotID, subjectHash, timestamp string, metadata map[string]any) bool {
+	return b.BallotID == ballotID && b.SubjectHash == subjectHash && b.Timestamp == timestamp &&
+		reflect.DeepEqual(b.Metadata, metadata)
+}
+
+// putPrivateBallot stores the full ballot record in a private data collection.
+func putPrivateBallot(ctx contractapi.TransactionContextInterface, collection, key string, ballot *BallotCommitment) error {
+	bytes, err := marshalState(ballot)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutPrivateData(collection, key, bytes)
+}
+
+// getBallot reads the ballot commitment stored for an election and hash.
+func getBallot(ctx contractapi.TransactionContextInterface, electionID, commitmentHash string) (*BallotCommitment, error) {
+	key, err := ballotKey(ctx, electionID, commitmentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if bytes == nil {
+		return nil, codedErrorf(ErrCodeNotFound, "ballot commitment not found")
+	}
+
+	var ballot BallotCommitment
+	if err := unmarshalState(bytes, &ballot); err != nil {
+		return nil, err
+	}
+	return &ballot, nil
+}
+
+func putBallot(ctx contractapi.TransactionContextInterface, key string, ballot *BallotCommitment) error {
+	bytes, err := marshalState(ballot)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, bytes)
+}
+
+// stampBallot sets RecordedAt from the transaction timestamp and flags the
+// ballot when its client-supplied Timestamp is outside the election's skew
+// tolerance or cannot be parsed. An empty client timestamp is not flagged.
+func stampBallot(ctx contractapi.TransactionContextInterface, election *Election, ballot *BallotCommitment) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	ballot.RecordedAt = now.Format(time.RFC3339Nano)
+
+	if ballot.Timestamp == "" {
+		return nil
+	}
+	claimed, err := parseTimestamp(ballot.Timestamp)
+	if err != nil {
+		ballot.ClockSkewed = true
+		return nil
+	}
+	skew := now.Sub(claimed)
+	if skew < 0 {
+		skew = -skew
+	}
+	ballot.ClockSkewed = skew > election.maxClockSkew()
+	return nil
+}
+
+// indexBallot records the election a commitment hash was first submitted in.
+func indexBallot(ctx contractapi.TransactionContextInterface, electionID, commitmentHash string) error {
+	indexKey := ballotIndexKey(commitmentHash)
+	indexed, err := ctx.GetStub().GetState(indexKey)
+	if err != nil {
+		return err
+	}
+	if indexed != nil {
+		return nil
+	}
+	return ctx.GetStub().PutState(indexKey, []byte(electionID))
+}
+
+// indexBallotSubject records the ballot under its subject, if it has one.
+func indexBallotSubject(ctx contractapi.TransactionContextInterface, ballot *BallotCommitment) error {
+	if ballot.SubjectHash == "" {
+		return nil
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(
+		ballotSubjectIndex, []string{ballot.ElectionID, ballot.SubjectHash, ballot.CommitmentHash},
+	)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(indexKey, []byte{0x00})
+}
+
+// SubmitSubjectBallotCommitment behaves like SubmitBallotCommitment but also
+// records the subject that submitted the ballot, so the subject can later list
+// their ballots with GetBallotsBySubject.
+func (c *BallotContract) SubmitSubjectBallotCommitment(
+	ctx contractapi.TransactionContextInterface,
+	electionID, ballotID, commitmentHash, subjectHash, timestamp, metadataJSON string,
+) error {
+	if subjectHash == "" {
+		return codedErrorf(ErrCodeInvalidArgument, "subject hash is required")
+	}
+	return submitBallot(ctx, "", electionID, ballotID, commitmentHash, subjectHash, timestamp, metadataJSON)
+}
+
+// ballotChainKey holds the commitment hash of the last ballot a subject
+// submitted in an election.
+func ballotChainKey(electionID, subjectHash string) string {
+	return fmt.Sprintf("ballotchain:%s:%s", electionID, subjectHash)
+}
+
+// ballotChainHead returns the commitment hash of the last ballot a subject
+// submitted in an election, or "" if they have submitted none.
+func ballotChainHead(ctx contractapi.TransactionContextInterface, electionID, subjectHash string) (string, error) {
+	head, err := ctx.GetStub().GetState(ballotChainKey(electionID, subjectHash))
+	if err != nil {
+		return "", err
+	}
+	return string(head), nil
+}
+
+// advanceBallotChain makes a subject's ballot the head of their chain. Ballots
+// without a subject are not chained.
+func advanceBallotChain(ctx contractapi.TransactionContextInterface, ballot *BallotCommitment) error {
+	if ballot.SubjectHash == "" {
+		return nil
+	}
+	return ctx.GetStub().PutState(ballotChainKey(ballot.ElectionID, ballot.SubjectHash), []byte(ballot.CommitmentHash))
+}
+
+// isZeroHash reports whether hash is empty or all zeros, the values accepted
+// as the predecessor of a subject's first ballot.
+func isZeroHash(hash string) bool {
+	return strings.Trim(hash, "0") == ""
+}
+
+// SubmitChainedBallotCommitment behaves like SubmitSubjectBallotCommitment but
+// also takes the commitment hash of the subject's previous ballot in the
+// election, which must match the last one recorded, so clients prove they
+// know the submission order. For the subject's first ballot it must be empty
+// or all zeros. Every ballot submitted with a subject is chained this way and
+// records its predecessor in PrevCommitmentHash.
+func (c *BallotContract) SubmitChainedBallotCommitment(
+	ctx contractapi.TransactionContextInterface,
+	electionID, ballotID, commitmentHash, subjectHash, prevCommitmentHash, timestamp, metadataJSON string,
+) error {
+	if err := validateInputs(
+		requireKeyID("subject hash", subjectHash),
+		maxLength("previous commitment hash", prevCommitmentHash, maxHashLength),
+	); err != nil {
+		return err
+	}
+
+	// Resubmissions are left to submitBallot's duplicate handling, since the
+	// chain has already moved past them
+	key, err := ballotKey(ctx, electionID, commitmentHash)
+	if err != nil {
+		return err
+	}
+	exists, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return err
+	}
+	if exists == nil {
+		head, err := ballotChainHead(ctx, electionID, subjectHash)
+		if err != nil {
+			return err
+		}
+		if head == "" && !isZeroHash(prevCommitmentHash) {
+			return codedErrorf(ErrCodeChainMismatch, "subject has no earlier ballot in election %s; previous commitment hash must be empty", electionID)
+		}
+		if head != "" && !strings.EqualFold(head, prevCommitmentHash) {
+			return codedErrorf(ErrCodeChainMismatch, "previous commitment hash does not match the subject's last ballot %s", head)
+		}
+	}
+	return submitBallot(ctx, "", electionID, ballotID, commitmentHash, subjectHash, timestamp, metadataJSON)
+}
+
+// GetBallotsBySubject returns every ballot commitment a subject submitted in an
+// election, or an empty list if there are none.
+func (c *BallotContract) GetBallotsBySubject(
+	ctx contractapi.TransactionContextInterface,
+	electionID, subjectHash string,
+) ([]BallotCommitment, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(ballotSubjectIndex, []string{electionID, subjectHash})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	ballots := []BallotCommitment{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(record.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) != 3 {
+			continue
+		}
+
+		key, err := ballotKey(ctx, electionID, parts[2])
+		if err != nil {
+			return nil, err
+		}
+		bytes, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, err
+		}
+		if bytes == nil {
+			continue
+		}
+
+		var ballot BallotCommitment
+		if err := unmarshalState(bytes, &ballot); err != nil {
+			return nil, err
+		}
+		ballots = append(ballots, ballot)
+	}
+
+	return ballots, nil
+}
+
+// SubmitBallotCommitmentPrivate records a ballot commitment whose metadata
+// must not be replicated to every peer. The metadata is passed in the
+// transient map under "metadata" so it never appears in the transaction, and
+// the full record is written to the named private data collection. The public
+// ledger keeps the commitment without metadata, so the hash stays publicly
+// verifiable.
+func (c *BallotContract) SubmitBallotCommitmentPrivate(
+	ctx contractapi.TransactionContextInterface,
+	collection, electionID, ballotID, commitmentHash, timestamp string,
+) error {
+	if collection == "" {
+		return codedErrorf(ErrCodeInvalidArgument, "collection is required")
+	}
+
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return err
+	}
+	return submitBallot(ctx, collection, electionID, ballotID, commitmentHash, "", timestamp, string(transient["metadata"]))
+}
+
+// GetPrivateBallot returns the full ballot record, metadata included, from a
+// private data collection. Only peers of organizations that are members of
+// the collection hold the data.
+func (c *BallotContract) GetPrivateBallot(
+	ctx contractapi.TransactionContextInterface,
+	collection, electionID, commitmentHash string,
+) (*BallotCommitment, error) {
+	key, err := ballotKey(ctx, electionID, commitmentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := ctx.GetStub().GetPrivateData(collection, key)
+	if err != nil {
+		return nil, err
+	}
+	if bytes == nil {
+		return nil, codedErrorf(ErrCodeNotFound, "private ballot not found in collection %s", collection)
+	}
+
+	var ballot BallotCommitment
+	if err := unmarshalState(bytes, &ballot); err != nil {
+		return nil, err
+	}
+	return &ballot, nil
+}
+
+// GetBallotCommitmentForElection retrieves a ballot commitment from a known
+// election with a single point read of its key. Prefer it over
+// GetBallotCommitment, which must first resolve the election through the hash
+// index, whenever the election is known.
+func (c *BallotContract) GetBallotCommitmentForElection(
+	ctx contractapi.TransactionContextInterface,
+	electionID, commitmentHash string,
+) (*BallotCommitment, error) {
+	return getBallot(ctx, electionID, commitmentHash)
+}
+
+// GetBallotCommitmentByBallotID retrieves the ballot commitment recorded for
+// a caller-assigned ballot ID in an election, for clients that track their
+// ballots by ID rather than by commitment hash.
+func (c *BallotContract) GetBallotCommitmentByBallotID(
+	ctx contractapi.TransactionContextInterface,
+	electionID, ballotID string,
+) (*BallotCommitment, error) {
+	return getBallotByID(ctx, electionID, ballotID)
+}
+
+// GetBallotHistory returns every write to a ballot commitment, newest first,
+// including deletes. A single entry proves the commitment was never modified.
+// Requires the peer's history database to be enabled.
+func (c *BallotContract) GetBallotHistory(
+	ctx contractapi.TransactionContextInterface,
+	electionID, commitmentHash string,
+) ([]BallotHistoryEntry, error) {
+	key, err := ballotKey(ctx, electionID, commitmentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	history := []BallotHistoryEntry{}
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		entry := BallotHistoryEntry{
+			TxID:      modification.GetTxId(),
+			IsDeleted: modification.GetIsDelete(),
+		}
+		if ts := modification.GetTimestamp(); ts != nil {
+			entry.Timestamp = time.Unix(ts.GetSeconds(), int64(ts.GetNanos())).UTC().Format(time.RFC3339Nano)
+		}
+		if !entry.IsDeleted {
+			var ballot BallotCommitment
+			if err := unmarshalState(modification.GetValue(), &ballot); err != nil {
+				return nil, err
+			}
+			entry.Ballot = &ballot
+		}
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
+
+// SpoilBallot marks a ballot commitment as spoiled before the election closes.
+// The record is kept for audit rather than deleted. Only the ballot is
+// spoiled: votes live in their own namespace and are withdrawn with
+// InvalidateVote. Only election officials may call it. Emits a "BallotSpoiled"
+// event.
+func (c *BallotContract) SpoilBallot(
+	ctx contractapi.TransactionContextInterface,
+	electionID, commitmentHash, reason string,
+) error {
+	if err := validateInputs(
+		requireHash("commitment hash", commitmentHash),
+		maxLength("spoil reason", reason, maxTextLength),
+	); err != nil {
+		return err
+	}
+	if reason == "" {
+		return codedErrorf(ErrCodeInvalidArgument, "spoil reason is required")
+	}
+	if err := requireMSP(ctx, officialMSPs, "election official"); err != nil {
+		return err
+	}
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if election.Status != StatusOpen && election.Status != StatusPaused {
+		return codedErrorf(ErrCodeElectionNotOpen, "ballots can only be spoiled while election %s is open (status %s)", electionID, election.Status)
+	}
+
+	key, err := ballotKey(ctx, electionID, commitmentHash)
+	if err != nil {
+		return err
+	}
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return err
+	}
+	if bytes == nil {
+		return codedErrorf(ErrCodeNotFound, "ballot commitment not found")
+	}
+
+	var ballot BallotCommitment
+	if err := unmarshalState(bytes, &ballot); err != nil {
+		return err
+	}
+	if ballot.Spoiled {
+		return codedErrorf(ErrCodeInvalidStatus, "ballot already spoiled")
+	}
+
+	ballot.Spoiled = true
+	ballot.SpoiledReason = reason
+	if err := putBallot(ctx, key, &ballot); err != nil {
+		return err
+	}
+
+	if err := addToCounter(ctx, spoiledBallotCountKey(electionID), 1); err != nil {
+		return err
+	}
+	if err := logEvent(ctx, electionID, EventBallotSpoiled, commitmentHash); err != nil {
+		return err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	return emitEvent(ctx, EventBallotSpoiled, BallotSpoiledEvent{
+		ElectionID:     electionID,
+		CommitmentHash: commitmentHash,
+		Reason:         reason,
+		TxID:           ctx.GetStub().GetTxID(),
+		Timestamp:      now.Format(time.RFC3339Nano),
+	})
+}
+
+// QueryBallots runs a CouchDB selector query and returns the matching ballot
+// commitments, e.g. {"selector":{"metadata.stationId":"X"}}. Documents that are
+// not ballot commitments are ignored. Requires CouchDB as the state database.
+func (c *BallotContract) QueryBallots(
+	ctx contractapi.TransactionContextInterface,
+	queryString string,
+) ([]BallotCommitment, error) {
+	if strings.TrimSpace(queryString) == "" {
+		return nil, fmt.Errorf("query string is required")
+	}
+
+	iterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "leveldb") {
+			return nil, fmt.Errorf("rich queries require CouchDB as the state database: %w", err)
+		}
+		return nil, err
+	}
+	defer iterator.Close()
+
+	prefix, err := ctx.GetStub().CreateCompositeKey(ballotObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	ballots := []BallotCommitment{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(record.Key, prefix) {
+			continue
+		}
+
+		var ballot BallotCommitment
+		if err := unmarshalState(record.Value, &ballot); err != nil {
+			return nil, err
+		}
+		ballots = append(ballots, ballot)
+	}
+
+	return ballots, nil
+}
+
+// ListBallots returns a page of the ballot commitments recorded for an
+// election. Pass the returned bookmark to fetch the next page; an empty
+// bookmark starts from the beginning.
+func (c *BallotContract) ListBallots(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	pageSize int,
+	bookmark string,
+) (*BallotPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		ballotObjectType, []string{electionID}, int32(pageSize), bookmark,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	ballots := []BallotCommitment{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var ballot BallotCommitment
+		if err := unmarshalState(record.Value, &ballot); err != nil {
+			return nil, err
+		}
+		ballots = append(ballots, ballot)
+	}
+
+	return &BallotPage{
+		Ballots:      ballots,
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: int(metadata.GetFetchedRecordsCount()),
+	}, nil
+}
+
+// GetBallotsByTimeRange returns the ballot commitments of one page of an
+// election's ballots whose client-supplied Timestamp falls between startTs and
+// endTs inclusive, for spotting submission bursts. Both bounds and the stored
+// timestamps are parsed as RFC3339, so they compare correctly across zones;
+// ballots without a parsable timestamp are left out. The page is taken before
+// filtering, so it may hold fewer than pageSize ballots, and FetchedCount is
+// the number of ballots scanned. Pass the returned bookmark to fetch the next
+// page; an empty bookmark starts from the beginning.
+func (c *BallotContract) GetBallotsByTimeRange(
+	ctx contractapi.TransactionContextInterface,
+	electionID, startTs, endTs string,
+	pageSize int,
+	bookmark string,
+) (*BallotPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+	start, err := parseTimestamp(startTs)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTimestamp(endTs)
+	if err != nil {
+		return nil, err
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end of time range must not be before its start")
+	}
+	if _, err := getElection(ctx, electionID); err != nil {
+		return nil, err
+	}
+
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		ballotObjectType, []string{electionID}, int32(pageSize), bookmark,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	ballots := []BallotCommitment{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var ballot BallotCommitment
+		if err := unmarshalState(record.Value, &ballot); err != nil {
+			return nil, err
+		}
+		submitted, err := parseTimestamp(ballot.Timestamp)
+		if err != nil || submitted.Before(start) || submitted.After(end) {
+			continue
+		}
+		ballots = append(ballots, ballot)
+	}
+
+	return &BallotPage{
+		Ballots:      ballots,
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: int(metadata.GetFetchedRecordsCount()),
+	}, nil
+}
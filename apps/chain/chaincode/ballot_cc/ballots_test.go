@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetBallotHistoryUnmodifiedBallot(t *testing.T) {
+	env := newTestEnv(t)
+	env.recordHistory()
+	env.openElection("e1", `{"title":"Board","options":["yes","no"]}`)
+	env.mustInvoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot1", testHash(1), "", "")
+	})
+
+	var history []BallotHistoryEntry
+	env.mustInvoke(func() (err error) {
+		history, err = env.contract.GetBallotHistory(env.ctx, "e1", testHash(1))
+		return err
+	})
+	if len(history) != 1 {
+		t.Fatalf("got %d history entries, want 1", len(history))
+	}
+	if history[0].IsDeleted || history[0].Ballot == nil || history[0].Ballot.BallotID != "ballot1" || history[0].Timestamp == "" {
+		t.Fatalf("got history entry %+v", history[0])
+	}
+}
+
+func TestGetBallotHistoryShowsOverwriteAndDelete(t *testing.T) {
+	env := newTestEnv(t)
+	env.recordHistory()
+	env.openElection("e1", `{"title":"Board","options":["yes","no"]}`)
+	env.mustInvoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot1", testHash(1), "", "")
+	})
+
+	var key string
+	env.mustInvoke(func() (err error) {
+		key, err = ballotKey(env.ctx, "e1", testHash(1))
+		return err
+	})
+	env.mustInvoke(func() error {
+		bytes, err := marshalState(BallotCommitment{ElectionID: "e1", BallotID: "tampered", CommitmentHash: testHash(1)})
+		if err != nil {
+			return err
+		}
+		return env.ctx.GetStub().PutState(key, bytes)
+	})
+	env.mustInvoke(func() error {
+		return env.ctx.GetStub().DelState(key)
+	})
+
+	var history []BallotHistoryEntry
+	env.mustInvoke(func() (err error) {
+		history, err = env.contract.GetBallotHistory(env.ctx, "e1", testHash(1))
+		return err
+	})
+	if len(history) != 3 {
+		t.Fatalf("got %d history entries, want 3", len(history))
+	}
+	if !history[0].IsDeleted || history[0].Ballot != nil {
+		t.Errorf("newest entry %+v is not the delete", history[0])
+	}
+	if history[1].Ballot == nil || history[1].Ballot.BallotID != "tampered" {
+		t.Errorf("second entry %+v is not the overwrite", history[1])
+	}
+	if history[2].Ballot == nil || history[2].Ballot.BallotID != "ballot1" {
+		t.Errorf("oldest entry %+v is not the submission", history[2])
+	}
+	if history[0].TxID == history[2].TxID {
+		t.Errorf("entries share transaction %s", history[0].TxID)
+	}
+}
+
+func TestIdempotentPolicyAcceptsIdenticalRetry(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"duplicatePolicy":"IDEMPOTENT"}`)
+	submit := func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot1", testHash(1), "2026-01-01T00:00:00Z", `{"station":"s1"}`)
+	}
+	env.mustInvoke(submit)
+
+	var key string
+	var before []byte
+	env.mustInvoke(func() (err error) {
+		if key, err = ballotKey(env.ctx, "e1", testHash(1)); err != nil {
+			return err
+		}
+		before, err = env.ctx.GetStub().GetState(key)
+		return err
+	})
+	env.mustInvoke(submit)
+
+	var after []byte
+	var count *BallotCount
+	env.mustInvoke(func() (err error) {
+		if after, err = env.ctx.GetStub().GetState(key); err != nil {
+			return err
+		}
+		count, err = env.contract.GetBallotCommitmentCount(env.ctx, "e1")
+		return err
+	})
+	if string(after) != string(before) {
+		t.Errorf("retry rewrote the ballot: %s, was %s", after, before)
+	}
+	if count.Total != 1 {
+		t.Errorf("got %d ballots after the retry, want 1", count.Total)
+	}
+}
+
+func TestIdempotentPolicyRejectsConflictingBallot(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"duplicatePolicy":"IDEMPOTENT"}`)
+	env.mustInvoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot1", testHash(1), "", "")
+	})
+
+	err := env.invoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot1", testHash(2), "", "")
+	})
+	wantCode(t, err, ErrCodeBallotConflict)
+
+	err = env.invoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot2", testHash(1), "", "")
+	})
+	wantCode(t, err, ErrCodeDuplicateCommitment)
+}
+
+func TestIdempotentPolicyAcceptsNewBallot(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"duplicatePolicy":"IDEMPOTENT"}`)
+	env.mustInvoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot1", testHash(1), "", "")
+	})
+	env.mustInvoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot2", testHash(2), "", "")
+	})
+}
+
+func TestRejectPolicyRejectsIdenticalRetry(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"]}`)
+	submit := func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot1", testHash(1), "", "")
+	}
+	env.mustInvoke(submit)
+
+	wantCode(t, env.invoke(submit), ErrCodeDuplicateCommitment)
+}
+
+func TestSpoilBallotRequiresOfficial(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"]}`)
+	env.mustInvoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot1", testHash(1), "", "")
+	})
+
+	env.setCaller("VoterOrgMSP")
+	err := env.invoke(func() error {
+		return env.contract.SpoilBallot(env.ctx, "e1", testHash(1), "torn")
+	})
+	wantCode(t, err, ErrCodeUnauthorized)
+}
+
+func TestSpoilBallotLeavesVoteWithSameHashCounted(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	env.mustInvoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot1", testHash(1), "", "")
+	})
+	env.mustInvoke(func() error {
+		return env.contract.SpoilBallot(env.ctx, "e1", testHash(1), "torn")
+	})
+
+	var tally *Tally
+	env.mustInvoke(func() (err error) {
+		tally, err = env.contract.TallyResults(env.ctx, "e1", "", false)
+		return err
+	})
+	if tally.Counts["yes"] != 1 {
+		t.Fatalf("got %d yes votes, want the vote to stay counted", tally.Counts["yes"])
+	}
+}
+
+// submittedBallot submits a ballot with a client timestamp and returns the
+// stored record.
+func (env *testEnv) submittedBallot(n int, timestamp string) *BallotCommitment {
+	env.t.Helper()
+	env.mustInvoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", fmt.Sprintf("ballot%d", n), testHash(n), timestamp, "")
+	})
+	var ballot *BallotCommitment
+	env.mustInvoke(func() (err error) {
+		ballot, err = env.contract.GetBallotCommitment(env.ctx, testHash(n))
+		return err
+	})
+	return ballot
+}
+
+func TestSubmitBallotFlagsSkewedClientTimestamp(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"]}`)
+
+	if ballot := env.submittedBallot(1, time.Now().UTC().Format(time.RFC3339)); ballot.ClockSkewed {
+		t.Errorf("ballot with a current timestamp was flagged")
+	}
+	if ballot := env.submittedBallot(2, "2000-01-01T00:00:00Z"); !ballot.ClockSkewed {
+		t.Errorf("ballot with a skewed timestamp was not flagged")
+	}
+}
+
+func TestSubmitBallotFlagsUnparseableClientTimestamp(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"]}`)
+
+	ballot := env.submittedBallot(1, "01/02/2026 10:00")
+	if !ballot.ClockSkewed || ballot.Timestamp != "01/02/2026 10:00" {
+		t.Fatalf("got ballot %+v, want the unparseable timestamp kept and flagged", ballot)
+	}
+
+	var result *BatchResult
+	env.mustInvoke(func() (err error) {
+		result, err = env.contract.BatchSubmitBallotCommitments(env.ctx, `[{"electionId":"e1","ballotId":"ballot2","commitmentHash":"`+testHash(2)+`","timestamp":"yesterday"}]`)
+		return err
+	})
+	if result.Accepted != 1 || result.Failed != 0 {
+		t.Fatalf("got batch result %+v, want the ballot accepted", result)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// BallotSubmission is one ballot in a BatchSubmitBallotCommitments request.
+type BallotSubmission struct {
+	ElectionID     string         `json:"electionId"`
+	BallotID       string         `json:"ballotId"`
+	CommitmentHash string         `json:"commitmentHash"`
+	SubjectHash    string         `json:"subjectHash,omitempty"`
+	Timestamp      string         `json:"timestamp"`
+	Metadata       map[string]any `json:"metadata"`
+}
+
+// BatchItemError describes why one item of a batch was not accepted.
+type BatchItemError struct {
+	Index          int    `json:"index"`
+	CommitmentHash string `json:"commitmentHash"`
+	Code           string `json:"code,omitempty"`
+	Error          string `json:"error"`
+}
+
+// BatchResult summarizes the outcome of a batch submission.
+type BatchResult struct {
+	Accepted int              `json:"accepted"`
+	Skipped  int              `json:"skipped"`
+	Failed   int              `json:"failed"`
+	Errors   []BatchItemError `json:"errors"`
+}
+
+// BatchSubmitBallotCommitments records many ballot commitments in a single
+// transaction. commitmentsJSON is a JSON array of BallotSubmission. Ballots
+// already on the ledger or repeated within the batch are skipped; invalid
+// ballots, including a second commitment for a ballot ID, are reported per
+// item without affecting the rest. A malformed request or a storage error
+// aborts the whole transaction. Emits a "BallotBatchCommitted" event listing
+// the accepted commitments.
+func (c *BallotContract) BatchSubmitBallotCommitments(
+	ctx contractapi.TransactionContextInterface,
+	commitmentsJSON string,
+) (*BatchResult, error) {
+	if err := validateInputs(maxLength("commitments", commitmentsJSON, maxBatchJSONLength)); err != nil {
+		return nil, err
+	}
+
+	var submissions []BallotSubmission
+	if err := json.Unmarshal([]byte(commitmentsJSON), &submissions); err != nil {
+		return nil, withCode(ErrCodeInvalidArgument, err)
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	result := &BatchResult{Errors: []BatchItemError{}}
+	accepted := []SubmissionEvent{}
+
+	// Writes made earlier in this transaction are not visible to GetState, so
+	// track election state, stored keys, indexed hashes, ballot IDs, the
+	// ballot counts of capped elections, the number of ballots added to each
+	// election and subjects' ballot chain heads in memory.
+	elections := map[string]*Election{}
+	electionErrs := map[string]error{}
+	stored := map[string]bool{}
+	indexed := map[string]bool{}
+	ballotIDs := map[string]bool{}
+	ballotCounts := map[string]int{}
+	storedCounts := map[string]int{}
+	chainHeads := map[string]string{}
+
+	fail := func(index int, submission BallotSubmission, err error) {
+		result.Failed++
+		result.Errors = append(result.Errors, BatchItemError{
+			Index:          index,
+			CommitmentHash: submission.CommitmentHash,
+			Code:           errorCode(err),
+			Error:          err.Error(),
+		})
+	}
+
+	for i, submission := range submissions {
+		if err := validateInputs(
+			requireKeyID("election ID", submission.ElectionID),
+			requireID("ballot ID", submission.BallotID),
+			requireHash("commitment hash", submission.CommitmentHash),
+			optionalID("subject hash", submission.SubjectHash),
+			maxLength("timestamp", submission.Timestamp, maxIDLength),
+		); err != nil {
+			fail(i, submission, err)
+			continue
+		}
+
+		election, checked := elections[submission.ElectionID]
+		if !checked {
+			var err error
+			election, err = requireElectionOpen(ctx, submission.ElectionID)
+			elections[submission.ElectionID] = election
+			electionErrs[submission.ElectionID] = err
+		}
+		if err := electionErrs[submission.ElectionID]; err != nil {
+			fail(i, submission, err)
+			continue
+		}
+		if err := election.checkCommitmentHash(submission.CommitmentHash); err != nil {
+			fail(i, submission, err)
+			continue
+		}
+		if err := election.checkBallotMetadata(submission.Metadata); err != nil {
+			fail(i, submission, err)
+			continue
+		}
+
+		key, err := ballotKey(ctx, submission.ElectionID, submission.CommitmentHash)
+		if err != nil {
+			fail(i, submission, err)
+			continue
+		}
+		if stored[key] {
+			result.Skipped++
+			continue
+		}
+		exists, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, err
+		}
+		if exists != nil {
+			result.Skipped++
+			continue
+		}
+
+		idKey := ballotIDKey(submission.ElectionID, submission.BallotID)
+		if ballotIDs[idKey] {
+			fail(i, submission, codedErrorf(ErrCodeBallotConflict, "ballot %s already has a different commitment", submission.BallotID))
+			continue
+		}
+		if err := requireBallotIDUnused(ctx, submission.ElectionID, submission.BallotID); err != nil {
+			fail(i, submission, err)
+			continue
+		}
+		if election.MaxTotalVotes > 0 {
+			count, read := storedCounts[submission.ElectionID]
+			if !read {
+				if count, err = readCounter(ctx, ballotCountKey(submission.ElectionID)); err != nil {
+					return nil, err
+				}
+				storedCounts[submission.ElectionID] = count
+			}
+			if err := election.checkCapacity(count + ballotCounts[submission.ElectionID]); err != nil {
+				fail(i, submission, err)
+				continue
+			}
+		}
+
+		commitment := BallotCommitment{
+			ElectionID:     submission.ElectionID,
+			BallotID:       submission.BallotID,
+			CommitmentHash: submission.CommitmentHash,
+			SubjectHash:    submission.SubjectHash,
+			Timestamp:      submission.Timestamp,
+			Metadata:       submission.Metadata,
+			TxID:           txID,
+		}
+		if err := stampBallot(ctx, election, &commitment); err != nil {
+			fail(i, submission, err)
+			continue
+		}
+		chainKey := ballotChainKey(submission.ElectionID, submission.SubjectHash)
+		if submission.SubjectHash != "" {
+			head, read := chainHeads[chainKey]
+			if !read {
+				if head, err = ballotChainHead(ctx, submission.ElectionID, submission.SubjectHash); err != nil {
+					return nil, err
+				}
+			}
+			commitment.PrevCommitmentHash = head
+		}
+		if err := putBallot(ctx, key, &commitment); err != nil {
+			return nil, err
+		}
+		stored[key] = true
+
+		if !indexed[submission.CommitmentHash] {
+			if err := indexBallot(ctx, submission.ElectionID, submission.CommitmentHash); err != nil {
+				return nil, err
+			}
+			indexed[submission.CommitmentHash] = true
+		}
+
+		if err := ctx.GetStub().PutState(idKey, []byte(submission.CommitmentHash)); err != nil {
+			return nil, err
+		}
+		ballotIDs[idKey] = true
+
+		if err := indexBallotSubject(ctx, &commitment); err != nil {
+			return nil, err
+		}
+		if err := advanceBallotChain(ctx, &commitment); err != nil {
+			return nil, err
+		}
+		if submission.SubjectHash != "" {
+			chainHeads[chainKey] = submission.CommitmentHash
+		}
+		ballotCounts[submission.ElectionID]++
+
+		if err := logSubmission(ctx, submission.ElectionID, EventBallotBatchCommitted, submission.CommitmentHash); err != nil {
+			return nil, err
+		}
+
+		result.Accepted++
+		accepted = append(accepted, SubmissionEvent{
+			ElectionID:     submission.ElectionID,
+			CommitmentHash: submission.CommitmentHash,
+			TxID:           txID,
+		})
+	}
+
+	electionIDs := make([]string, 0, len(ballotCounts))
+	for electionID := range ballotCounts {
+		electionIDs = append(electionIDs, electionID)
+	}
+	sort.Strings(electionIDs)
+	for _, electionID := range electionIDs {
+		if err := addToCounter(ctx, ballotCountKey(electionID), ballotCounts[electionID]); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(accepted) > 0 {
+		if err := emitEvent(ctx, EventBallotBatchCommitted, accepted); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
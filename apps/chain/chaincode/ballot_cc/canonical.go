@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// marshalState serializes a value for PutState in a canonical form, so every
+// endorsing peer produces byte-identical write sets for the same record.
+// Object keys are sorted at every level and numbers are normalized: integers
+// are written without fraction or exponent and other numbers in the shortest
+// form that round-trips through float64. Records embedding Record are
+// stamped with the current schema version.
+func marshalState(v any) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if object, ok := value.(map[string]any); ok {
+		if _, versioned := v.(versionedRecord); versioned {
+			object["schemaVersion"] = json.Number(strconv.Itoa(currentSchemaVersion))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case json.Number:
+		number, err := canonicalNumber(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(number)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	}
+	return nil
+}
+
+// canonicalNumber normalizes a JSON number so that equal values share one
+// spelling, e.g. 1, 1.0 and 1e0 all become 1. Integer literals too large for
+// int64 are kept verbatim rather than rounded through float64.
+func canonicalNumber(n json.Number) (string, error) {
+	literal := n.String()
+	if !strings.ContainsAny(literal, ".eE") {
+		if i, err := strconv.ParseInt(literal, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10), nil
+		}
+		return literal, nil
+	}
+
+	f, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return "", err
+	}
+	if f == float64(int64(f)) && f >= -1<<53 && f <= 1<<53 {
+		return strconv.FormatInt(int64(f), 10), nil
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64), nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMarshalStateIgnoresInsertionOrder(t *testing.T) {
+	first := map[string]any{}
+	first["station"] = "s1"
+	first["device"] = map[string]any{"id": "d1", "battery": 0.5}
+	first["turnout"] = []any{3, 1.0}
+
+	second := map[string]any{}
+	second["turnout"] = []any{3, 1.0}
+	second["device"] = map[string]any{"battery": 0.5, "id": "d1"}
+	second["station"] = "s1"
+
+	a, err := marshalState(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := marshalState(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != string(b) {
+		t.Fatalf("equivalent maps serialized differently:\n%s\n%s", a, b)
+	}
+	if want := `{"device":{"battery":0.5,"id":"d1"},"station":"s1","turnout":[3,1]}`; string(a) != want {
+		t.Fatalf("got %s, want %s", a, want)
+	}
+}
+
+func TestMarshalStateNormalizesNumbers(t *testing.T) {
+	spellings := []string{
+		`{"b":{"y":2,"x":1},"a":[1e0,2.50]}`,
+		`{"a":[1.0,25e-1],"b":{"x":1.00,"y":2E0}}`,
+	}
+	var want string
+	for i, spelling := range spellings {
+		var value map[string]any
+		decoder := json.NewDecoder(strings.NewReader(spelling))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			t.Fatal(err)
+		}
+		bytes, err := marshalState(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			want = string(bytes)
+		} else if string(bytes) != want {
+			t.Fatalf("%s serialized as %s, want %s", spelling, bytes, want)
+		}
+	}
+	if want != `{"a":[1,2.5],"b":{"x":1,"y":2}}` {
+		t.Fatalf("got %s", want)
+	}
+}
+
+func TestMarshalStateStampsRecords(t *testing.T) {
+	bytes, err := marshalState(BallotCommitment{ElectionID: "e1", Metadata: map[string]any{"b": 1, "a": 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(bytes, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["schemaVersion"] != float64(currentSchemaVersion) {
+		t.Fatalf("got schema version %v, want %d", decoded["schemaVersion"], currentSchemaVersion)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const challengeObjectType = "challenge"
+
+// Challenge states. A challenge is filed OPEN and resolved as UPHELD or
+// DISMISSED.
+const (
+	ChallengeOpen      = "OPEN"
+	ChallengeUpheld    = "UPHELD"
+	ChallengeDismissed = "DISMISSED"
+)
+
+// Challenge is an observer's dispute of a recorded vote or ballot commitment.
+type Challenge struct {
+	Record
+
+	ChallengeID    string `json:"challengeId"`
+	ElectionID     string `json:"electionId"`
+	CommitmentHash string `json:"commitmentHash"`
+	ChallengerID   string `json:"challengerId"`
+	Reason         string `json:"reason"`
+	Status         string `json:"status"`
+	FiledAt        string `json:"filedAt"`
+
+	// ResolverID is the client identity ID of the official who resolved the
+	// challenge.
+	ResolverID string `json:"resolverId,omitempty"`
+	ResolvedAt string `json:"resolvedAt,omitempty"`
+}
+
+// challengeIndexKey maps a challenge ID to the key the challenge is stored under.
+func challengeIndexKey(challengeID string) string {
+	return fmt.Sprintf("challengeidx:%s", challengeID)
+}
+
+func putChallenge(ctx contractapi.TransactionContextInterface, key string, challenge *Challenge) error {
+	bytes, err := marshalState(challenge)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, bytes)
+}
+
+// FileChallenge disputes a vote or ballot commitment recorded in an election
+// and returns the new challenge's ID, which is the filing transaction's ID.
+// A commitment may be challenged more than once.
+func (c *BallotContract) FileChallenge(
+	ctx contractapi.TransactionContextInterface,
+	electionID, commitmentHash, challengerID, reason string,
+) (string, error) {
+	if err := validateInputs(
+		requireHash("commitment hash", commitmentHash),
+		requireID("challenger ID", challengerID),
+		maxLength("reason", reason, maxTextLength),
+	); err != nil {
+		return "", err
+	}
+	if reason == "" {
+		return "", codedErrorf(ErrCodeInvalidArgument, "challenge reason is required")
+	}
+
+	if _, err := getElection(ctx, electionID); err != nil {
+		return "", err
+	}
+	recorded, err := commitmentRecorded(ctx, electionID, commitmentHash)
+	if err != nil {
+		return "", err
+	}
+	if !recorded {
+		return "", codedErrorf(ErrCodeNotFound, "commitment not found in election %s", electionID)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	challengeID := ctx.GetStub().GetTxID()
+	key, err := ctx.GetStub().CreateCompositeKey(challengeObjectType, []string{electionID, commitmentHash, challengeID})
+	if err != nil {
+		return "", err
+	}
+	if err := putChallenge(ctx, key, &Challenge{
+		ChallengeID:    challengeID,
+		ElectionID:     electionID,
+		CommitmentHash: commitmentHash,
+		ChallengerID:   challengerID,
+		Reason:         reason,
+		Status:         ChallengeOpen,
+		FiledAt:        now.Format(time.RFC3339Nano),
+	}); err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(challengeIndexKey(challengeID), []byte(key)); err != nil {
+		return "", err
+	}
+	if err := logEvent(ctx, electionID, EventLogChallengeFiled, challengeID, commitmentHash); err != nil {
+		return "", err
+	}
+
+	return challengeID, nil
+}
+
+// commitmentRecorded reports whether a vote or ballot commitment with the
+// given hash exists in an election.
+func commitmentRecorded(ctx contractapi.TransactionContextInterface, electionID, commitmentHash string) (bool, error) {
+	for _, objectType := range []string{voteObjectType, ballotObjectType} {
+		key, err := ctx.GetStub().CreateCompositeKey(objectType, []string{electionID, commitmentHash})
+		if err != nil {
+			return false, err
+		}
+		bytes, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return false, err
+		}
+		if bytes != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ResolveChallenge closes an OPEN challenge. resolution must be UPHELD or
+// DISMISSED. The caller's client identity is recorded as the resolver.
+// Challenges cannot be resolved once their election is CERTIFIED. Only
+// election officials may call it.
+func (c *BallotContract) ResolveChallenge(
+	ctx contractapi.TransactionContextInterface,
+	challengeID, resolution string,
+) error {
+	if resolution != ChallengeUpheld && resolution != ChallengeDismissed {
+		return codedErrorf(ErrCodeInvalidArgument, "resolution must be %s or %s", ChallengeUpheld, ChallengeDismissed)
+	}
+	if err := requireMSP(ctx, officialMSPs, "election official"); err != nil {
+		return err
+	}
+	resolverID, err := clientID(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().GetState(challengeIndexKey(challengeID))
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return codedErrorf(ErrCodeNotFound, "challenge %s not found", challengeID)
+	}
+	bytes, err := ctx.GetStub().GetState(string(key))
+	if err != nil {
+		return err
+	}
+	if bytes == nil {
+		return codedErrorf(ErrCodeNotFound, "challenge %s not found", challengeID)
+	}
+
+	var challenge Challenge
+	if err := unmarshalState(bytes, &challenge); err != nil {
+		return err
+	}
+	if challenge.Status != ChallengeOpen {
+		return codedErrorf(ErrCodeInvalidStatus, "challenge %s is already %s", challengeID, challenge.Status)
+	}
+
+	election, err := getElection(ctx, challenge.ElectionID)
+	if err != nil {
+		return err
+	}
+	switch election.Status {
+	case StatusCertified, StatusArchived:
+		return codedErrorf(ErrCodeInvalidStatus, "challenges cannot be resolved while election %s is %s", challenge.ElectionID, election.Status)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	challenge.Status = resolution
+	challenge.ResolverID = resolverID
+	challenge.ResolvedAt = now.Format(time.RFC3339Nano)
+	if err := putChallenge(ctx, string(key), &challenge); err != nil {
+		return err
+	}
+	return logEvent(ctx, challenge.ElectionID, EventLogChallengeResolved, challengeID, challenge.CommitmentHash)
+}
+
+// ListChallenges returns every challenge filed in an election, grouped by
+// commitment hash, for review.
+func (c *BallotContract) ListChallenges(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) ([]Challenge, error) {
+	return listChallenges(ctx, electionID)
+}
+
+func listChallenges(ctx contractapi.TransactionContextInterface, electionID string) ([]Challenge, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(challengeObjectType, []string{electionID})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	challenges := []Challenge{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var challenge Challenge
+		if err := unmarshalState(record.Value, &challenge); err != nil {
+			return nil, err
+		}
+		challenges = append(challenges, challenge)
+	}
+
+	return challenges, nil
+}
+
+// contestedCommitments returns the commitment hashes in an election with an
+// OPEN or UPHELD challenge.
+func contestedCommitments(ctx contractapi.TransactionContextInterface, electionID string) (map[string]bool, error) {
+	challenges, err := listChallenges(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	contested := map[string]bool{}
+	for _, challenge := range challenges {
+		if challenge.Status != ChallengeDismissed {
+			contested[challenge.CommitmentHash] = true
+		}
+	}
+	return contested, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Contest is one race on a multi-contest ballot, such as president or senate.
+// Each subject may cast one vote per contest.
+type Contest struct {
+	ContestID string   `json:"contestId"`
+	Options   []string `json:"options"`
+}
+
+// contest returns the election's contest with the given ID, or nil.
+func (e *Election) contest(contestID string) *Contest {
+	for i := range e.Contests {
+		if e.Contests[i].ContestID == contestID {
+			return &e.Contests[i]
+		}
+	}
+	return nil
+}
+
+// checkVoteOption checks that a vote names a valid option of its contest, or
+// of the election itself when it has no contests.
+func (e *Election) checkVoteOption(vote *VoteCommitment) error {
+	options := e.Options
+	if len(e.Contests) > 0 || vote.ContestID != "" {
+		if vote.ContestID == "" {
+			return codedErrorf(ErrCodeInvalidOption, "contest ID is required for election %s", e.ElectionID)
+		}
+		contest := e.contest(vote.ContestID)
+		if contest == nil {
+			return codedErrorf(ErrCodeInvalidOption, "unknown contest %s for election %s", vote.ContestID, e.ElectionID)
+		}
+		if len(vote.Preferences) > 0 {
+			return codedErrorf(ErrCodeInvalidOption, "ranked votes are not supported in contests")
+		}
+		options = contest.Options
+	}
+
+	if len(options) == 0 {
+		return codedErrorf(ErrCodeInvalidOption, "election has no options defined")
+	}
+	if !vote.WriteIn && vote.OptionID != AbstainOptionID && !containsOption(options, vote.OptionID) {
+		return codedErrorf(ErrCodeInvalidOption, "invalid option for election")
+	}
+	return validatePreferences(e, vote.Preferences)
+}
+
+// validateContests checks an election's contest list. Contest IDs must be
+// unique and may not contain "/", which separates contest and option IDs in
+// election-wide tallies.
+func validateContests(contests []Contest) error {
+	seen := map[string]bool{}
+	for _, contest := range contests {
+		if err := requireKeyID("contest ID", contest.ContestID); err != nil {
+			return err
+		}
+		if strings.Contains(contest.ContestID, "/") {
+			return fmt.Errorf("contest ID %q may not contain \"/\"", contest.ContestID)
+		}
+		if seen[contest.ContestID] {
+			return fmt.Errorf("duplicate contest ID %q", contest.ContestID)
+		}
+		seen[contest.ContestID] = true
+		if len(contest.Options) == 0 {
+			return fmt.Errorf("contest %s has no options defined", contest.ContestID)
+		}
+		if err := validateOptions(contest.Options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contestTallyKey is the key a contest's option is counted under in an
+// election-wide tally.
+func contestTallyKey(contestID, optionID string) string {
+	return contestID + "/" + optionID
+}
+
+// tallyOption is the option a vote is counted under in an election-wide tally.
+func (v *VoteCommitment) tallyOption() string {
+	if v.ContestID != "" {
+		return contestTallyKey(v.ContestID, v.OptionID)
+	}
+	return v.OptionID
+}
+
+// votedContestKey holds the ID of the transaction that recorded a subject's
+// vote in one contest of an election.
+func votedContestKey(electionID, contestID, subjectHash string) string {
+	return fmt.Sprintf("voted:%s:%s:%s", electionID, subjectHash, contestID)
+}
+
+// CastContestVote records a vote in one contest of a multi-contest election.
+// A subject may vote once in each contest; a second vote in the same contest
+// fails with ERR_ALREADY_VOTED.
+func (c *BallotContract) CastContestVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID, contestID, subjectHash, commitmentHash, optionID, metaJSON string,
+) error {
+	if err := validateInputs(
+		requireKeyID("contest ID", contestID),
+		maxLength("metadata", metaJSON, maxJSONLength),
+	); err != nil {
+		return err
+	}
+
+	var meta map[string]any
+	if err := json.Unmarshal([]byte(metaJSON), &meta); err != nil {
+		return withCode(ErrCodeInvalidArgument, err)
+	}
+
+	return recordVote(ctx, &VoteCommitment{
+		ElectionID:     electionID,
+		ContestID:      contestID,
+		SubjectHash:    subjectHash,
+		CommitmentHash: commitmentHash,
+		OptionID:       optionID,
+		Meta:           meta,
+	})
+}
@@ -2,80 +2,194 @@ package main
 
 import (
 "encoding/json"
-"fmt"
+"strings"
+"time"
 
 "github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// Composite key object types. Votes and ballots are stored keyed by
+// (electionID, commitmentHash); voteCommitmentIndex maps a commitment hash back
+// to the election it was cast in so receipts can be looked up by hash alone,
+// voteSubjectIndex and ballotSubjectIndex list the votes and ballots a
+// subject submitted in an election, and voteOptionIndex lists the votes for
+// each option.
+const (
+	voteObjectType      = "vote"
+	voteCommitmentIndex = "commitment~election"
+	voteSubjectIndex    = "subject~vote"
+	voteOptionIndex     = "option~vote"
+	ballotObjectType    = "ballot"
+	ballotSubjectIndex  = "subject~ballot"
+)
+
 // BallotContract implements Fabric smart contract for ObserverNet elections.
 type BallotContract struct {
 contractapi.Contract
 }
 
+// GetEvaluateTransactions lists the functions tagged as read-only evaluate
+// transactions in the contract metadata.
+func (c *BallotContract) GetEvaluateTransactions() []string {
+	return []string{"PreviewTally", "CountVotesByOption", "BallotExists", "VoteExists", "SubjectExists", "GetAPIInfo", "IsSubjectRegisteredElsewhere"}
+}
+
 // VoteCommitment represents a recorded vote.
 type VoteCommitment struct {
+	Record
+
 	ElectionID     string         `json:"electionId"`
+	ContestID      string         `json:"contestId,omitempty"`
 	SubjectHash    string         `json:"subjectHash"`
 	CommitmentHash string         `json:"commitmentHash"`
 	OptionID       string         `json:"optionId"`
+	Preferences    []string       `json:"preferences,omitempty"`
+	WriteIn        bool           `json:"writeIn,omitempty"`
+	WriteInText    string         `json:"writeInText,omitempty"`
+	Nonce          string         `json:"nonce,omitempty"`
 	Meta           map[string]any `json:"meta"`
+	TxID           string         `json:"txId,omitempty"`
+
+	// RecordedAt is the timestamp of the transaction that recorded the vote.
+	// It is empty on votes recorded before it was introduced.
+	RecordedAt string `json:"recordedAt,omitempty"`
+
+	// Weight is the weight the vote was counted with: the subject's
+	// registered weight in a weighted election, 1 otherwise.
+	Weight int `json:"weight,omitempty"`
+
+	// Set by InvalidateVote. Invalidated votes are kept for audit but not
+	// counted.
+	Invalidated       bool   `json:"invalidated,omitempty"`
+	InvalidatedReason string `json:"invalidatedReason,omitempty"`
+	InvalidatedBy     string `json:"invalidatedBy,omitempty"`
+
+	// Set on votes cast in a sealed election, which carry no option until
+	// RevealVote records it together with the salt it was committed with.
+	// Sealed votes are only counted once revealed. Expired is set by
+	// ExpireStaleCommitments on a sealed vote left unrevealed for longer than
+	// the election's RevealTTLSeconds; it can no longer be revealed.
+	Sealed     bool   `json:"sealed,omitempty"`
+	Revealed   bool   `json:"revealed,omitempty"`
+	RevealSalt string `json:"revealSalt,omitempty"`
+	Expired    bool   `json:"expired,omitempty"`
+
+	// Set on votes cast with CastProvisionalVote. A provisional vote is only
+	// counted once ProvisionalStatus is ProvisionalConfirmed.
+	Provisional           bool   `json:"provisional,omitempty"`
+	ProvisionalStatus     string `json:"provisionalStatus,omitempty"`
+	ProvisionalReason     string `json:"provisionalReason,omitempty"`
+	ProvisionalReviewedBy string `json:"provisionalReviewedBy,omitempty"`
+
+	// Anomalous is set when the vote took its option past the election's
+	// MaxVotesPerOption. The vote is still counted.
+	Anomalous bool `json:"anomalous,omitempty"`
+
+	// DelegatedFrom is set on a vote a proxy cast for a subject who delegated
+	// their vote with RegisterProxy and names that subject. SubjectHash is the
+	// proxy's; the subject index lists the vote under the delegator.
+	DelegatedFrom string `json:"delegatedFrom,omitempty"`
+
+	// Set on votes cast with CastSignedVote.
+	VoterPubKey         string `json:"voterPubKey,omitempty"`
+	VoterSignature      string `json:"voterSignature,omitempty"`
+	VoterKeyFingerprint string `json:"voterKeyFingerprint,omitempty"`
 }
 
 // BallotCommitment represents a ballot submission record.
 type BallotCommitment struct {
+	Record
+
 	ElectionID     string         `json:"electionId"`
 	BallotID       string         `json:"ballotId"`
 	CommitmentHash string         `json:"commitmentHash"`
+	SubjectHash    string         `json:"subjectHash,omitempty"`
 	Timestamp      string         `json:"timestamp"`
 	Metadata       map[string]any `json:"metadata"`
 	TxID           string         `json:"txId"`
+	RecordedAt     string         `json:"recordedAt"`
+	ClockSkewed    bool           `json:"clockSkewed,omitempty"`
+	Spoiled        bool           `json:"spoiled,omitempty"`
+	SpoiledReason  string         `json:"spoiledReason,omitempty"`
+
+	// PrivateCollection names the private data collection holding the full
+	// record when the ballot was submitted privately.
+	PrivateCollection string `json:"privateCollection,omitempty"`
+
+	// PrevCommitmentHash is the subject's previous ballot commitment in the
+	// election, chaining each subject's ballots in submission order. It is
+	// empty for a subject's first ballot and for ballots without a subject.
+	PrevCommitmentHash string `json:"prevCommitmentHash,omitempty"`
 }
 
 // AuditLogEntry represents an audit log anchored to blockchain.
 type AuditLogEntry struct {
-	MerkleRoot string         `json:"merkleRoot"`
-	Timestamp  string         `json:"timestamp"`
-	BatchSize  int            `json:"batchSize"`
-	Metadata   map[string]any `json:"metadata"`
+	Record
+
+	ElectionID   string         `json:"electionId,omitempty"`
+	MerkleRoot   string         `json:"merkleRoot"`
+	PreviousRoot string         `json:"previousRoot,omitempty"`
+	Timestamp    string         `json:"timestamp"`
+	BatchSize    int            `json:"batchSize"`
+	Metadata     map[string]any `json:"metadata"`
+	TxID         string         `json:"txId,omitempty"`
 }
 
 // ElectionResult represents certified election results.
 type ElectionResult struct {
+	Record
+
 	ElectionID   string         `json:"electionId"`
 	ResultsHash  string         `json:"resultsHash"`
 	TotalVotes   int            `json:"totalVotes"`
 	CertifiedAt  string         `json:"certifiedAt"`
 	CertifierID  string         `json:"certifierId"`
 	Metadata     map[string]any `json:"metadata"`
+	SnapshotTxID string         `json:"snapshotTxId,omitempty"`
+
+	// Winner, Ties and Abstentions are copied from the certified tally
+	// snapshot. AmendResults, which supplies only a new hash, clears Winner
+	// and Ties and carries Abstentions over.
+	Winner      string   `json:"winner,omitempty"`
+	Ties        []string `json:"ties,omitempty"`
+	Abstentions int      `json:"abstentions,omitempty"`
+
+	// QuorumThreshold and TurnoutPercent are the election's quorum and its
+	// turnout at certification; QuorumMet reports whether the turnout reached
+	// the quorum, and is always set when the election has none.
+	QuorumThreshold float64 `json:"quorumThreshold,omitempty"`
+	TurnoutPercent  float64 `json:"turnoutPercent"`
+	QuorumMet       bool    `json:"quorumMet"`
+
+	// Set when the certification has been superseded by AmendResults.
+	Amended             bool   `json:"amended,omitempty"`
+	AmendmentReason     string `json:"amendmentReason,omitempty"`
+	PreviousResultsHash string `json:"previousResultsHash,omitempty"`
 }
 
 // RegisterSubject ensures each hashed voter is registered for the election.
+// The election must exist, be in its DRAFT or REGISTRATION phase, and the
+// transaction timestamp must fall within its registration window.
 func (c *BallotContract) RegisterSubject(ctx contractapi.TransactionContextInterface, electionID, subjectHash string) error {
-key := fmt.Sprintf("subject:%s:%s", electionID, subjectHash)
-exists, err := ctx.GetStub().GetState(key)
-if err != nil {
+if _, err := requireRegistrationOpen(ctx, electionID); err != nil {
 return err
 }
-if exists != nil {
-return nil
-}
-return ctx.GetStub().PutState(key, []byte("registered"))
+_, err := registerSubject(ctx, electionID, subjectHash)
+return err
 }
 
-// CastVote records a vote commitment on ledger.
+// CastVote records a vote commitment on ledger. The election must be OPEN, the
+// option must be one of the election's options or AbstainOptionID, the subject
+// must be registered unless the election allows unregistered voters, and each
+// subject may vote only once per election. A registered subject must wait the
+// election's MinVoteDelaySeconds after registering. Emits a "VoteCast" event.
 func (c *BallotContract) CastVote(ctx contractapi.TransactionContextInterface, electionID, subjectHash, commitmentHash, optionID, metaJSON string) error {
-key := fmt.Sprintf("vote:%s:%s", electionID, commitmentHash)
-exists, err := ctx.GetStub().GetState(key)
-if err != nil {
+if err := validateInputs(maxLength("metadata", metaJSON, maxJSONLength)); err != nil {
 return err
 }
-if exists != nil {
-return fmt.Errorf("commitment already exists")
-}
-
 var meta map[string]any
 if err := json.Unmarshal([]byte(metaJSON), &meta); err != nil {
-return err
+return withCode(ErrCodeInvalidArgument, err)
 }
 
 commitment := VoteCommitment{
@@ -86,35 +200,357 @@ OptionID:       optionID,
 Meta:           meta,
 }
 
-bytes, err := json.Marshal(commitment)
-if err != nil {
-return err
+return recordVote(ctx, &commitment)
 }
 
-return ctx.GetStub().PutState(key, bytes)
+// recordVote validates a vote against its election, rejecting unregistered
+// subjects unless the election allows them or the vote is provisional and
+// subjects still within the election's minimum vote delay, and stores it
+// together with the subject's voted marker, the vote counter and the
+// commitment, transaction and subject indexes. A proxy's vote also records a
+// vote for each subject who delegated to them (see RegisterProxy). A
+// provisional vote joins the option index and MaxVotesPerOption count only
+// once it is confirmed.
+func recordVote(ctx contractapi.TransactionContextInterface, commitment *VoteCommitment) error {
+	electionID, commitmentHash := commitment.ElectionID, commitment.CommitmentHash
+
+	if err := validateInputs(
+		requireKeyID("election ID", electionID),
+		requireKeyID("subject hash", commitment.SubjectHash),
+		requireHash("commitment hash", commitmentHash),
+		maxLength("option ID", commitment.OptionID, maxIDLength),
+		optionalID("nonce", commitment.Nonce),
+	); err != nil {
+		return err
+	}
+	if len(commitment.Preferences) > maxOptions {
+		return codedErrorf(ErrCodeInvalidOption, "at most %d preferences may be ranked", maxOptions)
+	}
+	if commitment.VoterSignature != "" {
+		if err := verifyVoterSignature(commitment); err != nil {
+			return err
+		}
+	}
+
+	election, err := requireElectionOpen(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if err := election.checkCommitmentHash(commitmentHash); err != nil {
+		return err
+	}
+	if election.Sealed {
+		if err := election.checkSealedVote(commitment); err != nil {
+			return err
+		}
+		commitment.Sealed = true
+	} else {
+		if err := withCode(ErrCodeInvalidArgument, requireID("option ID", commitment.OptionID)); err != nil {
+			return err
+		}
+		if err := election.checkVoteOption(commitment); err != nil {
+			return err
+		}
+	}
+	registered, err := ctx.GetStub().GetState(subjectKey(electionID, commitment.SubjectHash))
+	if err != nil {
+		return err
+	}
+	if registered == nil && !election.AllowUnregistered && !commitment.Provisional {
+		return codedErrorf(ErrCodeNotRegistered, "subject not registered")
+	}
+	if err := election.checkVoteDelay(ctx, registered); err != nil {
+		return err
+	}
+	delegated, err := ctx.GetStub().GetState(delegationKey(electionID, commitment.SubjectHash))
+	if err != nil {
+		return err
+	}
+	if delegated != nil {
+		return codedErrorf(ErrCodeVoteDelegated, "subject has delegated their vote to a proxy")
+	}
+	if commitment.Weight, err = subjectWeight(ctx, election, commitment.SubjectHash); err != nil {
+		return err
+	}
+	if election.RequireVoterSignatures && commitment.VoterKeyFingerprint == "" {
+		return codedErrorf(ErrCodeInvalidSignature, "election %s requires signed votes", electionID)
+	}
+	var voterKey string
+	if commitment.VoterKeyFingerprint != "" {
+		voterKey = voterKeyKey(electionID, commitment.VoterKeyFingerprint)
+		signer, err := ctx.GetStub().GetState(voterKey)
+		if err != nil {
+			return err
+		}
+		if signer != nil && string(signer) != commitment.SubjectHash {
+			return codedErrorf(ErrCodeInvalidSignature, "voter public key already used by another subject")
+		}
+	}
+
+	// Check the nonce first so replays are reported as such rather than as
+	// duplicate commitments or repeat voters
+	var nonceKey string
+	if commitment.Nonce != "" {
+		nonceKey = voteNonceKey(electionID, commitment.Nonce)
+		used, err := ctx.GetStub().GetState(nonceKey)
+		if err != nil {
+			return err
+		}
+		if used != nil {
+			return codedErrorf(ErrCodeDuplicateNonce, "duplicate nonce")
+		}
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{electionID, commitmentHash})
+	if err != nil {
+		return err
+	}
+	exists, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return err
+	}
+	if exists != nil {
+		return codedErrorf(ErrCodeDuplicateCommitment, "commitment already exists")
+	}
+
+	// The election-wide marker records the subject's first vote; in
+	// multi-contest elections a per-contest marker guards each contest
+	votedKey := votedKey(electionID, commitment.SubjectHash)
+	voted, err := ctx.GetStub().GetState(votedKey)
+	if err != nil {
+		return err
+	}
+	firstVote := voted == nil
+	if commitment.ContestID == "" && !firstVote {
+		return codedErrorf(ErrCodeAlreadyVoted, "subject already voted")
+	}
+	var contestKey string
+	if commitment.ContestID != "" {
+		contestKey = votedContestKey(electionID, commitment.ContestID, commitment.SubjectHash)
+		votedInContest, err := ctx.GetStub().GetState(contestKey)
+		if err != nil {
+			return err
+		}
+		if votedInContest != nil {
+			return codedErrorf(ErrCodeAlreadyVoted, "subject already voted in contest %s", commitment.ContestID)
+		}
+	}
+
+	// A proxy's vote also records one for each of their delegators
+	proxied, newVoters, err := delegatedVotes(ctx, election, commitment)
+	if err != nil {
+		return err
+	}
+	if commitment.Provisional && len(proxied) > 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "proxies cannot cast provisional votes")
+	}
+	added := len(newVoters)
+	if firstVote {
+		added++
+	}
+
+	// Only a capped election reads the vote count, since summing its shards
+	// conflicts with every concurrent vote
+	if added > 0 && election.MaxTotalVotes > 0 {
+		voters, err := readVoteCount(ctx, electionID)
+		if err != nil {
+			return err
+		}
+		if err := election.checkCapacity(voters + added - 1); err != nil {
+			return err
+		}
+	}
+
+	commitment.TxID = ctx.GetStub().GetTxID()
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	commitment.RecordedAt = now.Format(time.RFC3339Nano)
+	if election.MaxVotesPerOption > 0 && commitment.optionIndexed() {
+		if err := flagOptionBurst(ctx, election, commitment, 1+len(proxied)); err != nil {
+			return err
+		}
+	}
+
+	bytes, err := marshalState(commitment)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.GetStub().PutState(key, bytes); err != nil {
+		return err
+	}
+	if contestKey != "" {
+		if err := ctx.GetStub().PutState(contestKey, []byte(ctx.GetStub().GetTxID())); err != nil {
+			return err
+		}
+	}
+	if firstVote {
+		if err := ctx.GetStub().PutState(votedKey, []byte(ctx.GetStub().GetTxID())); err != nil {
+			return err
+		}
+		// Votes counted by the unsharded counter predate the timeline
+		legacyVoters, err := readCounter(ctx, voteCountKey(electionID))
+		if err != nil {
+			return err
+		}
+		if legacyVoters == 0 {
+			if err := recordFirstVote(ctx, electionID); err != nil {
+				return err
+			}
+		}
+	}
+	for _, vote := range proxied {
+		vote.TxID = commitment.TxID
+		vote.RecordedAt = commitment.RecordedAt
+		vote.Anomalous = commitment.Anomalous
+		if err := putDelegatedVote(ctx, vote); err != nil {
+			return err
+		}
+	}
+	if err := markDelegatorsVoted(ctx, electionID, newVoters); err != nil {
+		return err
+	}
+	if added > 0 {
+		if err := addToVoteCount(ctx, electionID, added); err != nil {
+			return err
+		}
+	}
+	if nonceKey != "" {
+		if err := ctx.GetStub().PutState(nonceKey, []byte(ctx.GetStub().GetTxID())); err != nil {
+			return err
+		}
+	}
+	if voterKey != "" {
+		if err := ctx.GetStub().PutState(voterKey, []byte(commitment.SubjectHash)); err != nil {
+			return err
+		}
+	}
+
+	indexKey, err := ctx.GetStub().CreateCompositeKey(voteCommitmentIndex, []string{commitmentHash, electionID})
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(indexKey, []byte{0x00}); err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(voteTxIndexKey(ctx.GetStub().GetTxID()), []byte(key)); err != nil {
+		return err
+	}
+	subjectIndexKey, err := ctx.GetStub().CreateCompositeKey(voteSubjectIndex, []string{electionID, commitment.SubjectHash, commitmentHash})
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(subjectIndexKey, []byte{0x00}); err != nil {
+		return err
+	}
+	if commitment.optionIndexed() {
+		if err := indexVoteOption(ctx, commitment); err != nil {
+			return err
+		}
+	}
+	if commitment.Provisional {
+		provisionalKey, err := ctx.GetStub().CreateCompositeKey(provisionalVoteIndex, []string{electionID, commitmentHash})
+		if err != nil {
+			return err
+		}
+		if err := ctx.GetStub().PutState(provisionalKey, []byte{0x00}); err != nil {
+			return err
+		}
+	}
+
+	return emitSubmissionEvent(ctx, EventVoteCast, electionID, commitmentHash)
 }
 
 // SubmitBallotCommitment records a ballot commitment on the blockchain.
 // This is called by the voting API after a voter submits their encrypted ballot.
+// The election must be OPEN and each ballot ID may carry only one commitment.
+// Under the IDEMPOTENT duplicate policy an identical resubmission succeeds
+// without changing state. Emits a "BallotCommitted" event.
 func (c *BallotContract) SubmitBallotCommitment(
 	ctx contractapi.TransactionContextInterface,
 	electionID, ballotID, commitmentHash, timestamp, metadataJSON string,
 ) error {
-	key := fmt.Sprintf("ballot:%s:%s", electionID, commitmentHash)
+	return submitBallot(ctx, "", electionID, ballotID, commitmentHash, "", timestamp, metadataJSON)
+}
 
-	// Check if commitment already exists (prevent double submission)
-	exists, err := ctx.GetStub().GetState(key)
+// submitBallot records a ballot commitment, optionally tied to a subject. When
+// collection is set the full record, metadata included, goes to that private
+// data collection and the public record carries no metadata.
+func submitBallot(
+	ctx contractapi.TransactionContextInterface,
+	collection, electionID, ballotID, commitmentHash, subjectHash, timestamp, metadataJSON string,
+) error {
+	if err := validateInputs(
+		optionalID("collection", collection),
+		requireKeyID("election ID", electionID),
+		requireID("ballot ID", ballotID),
+		requireHash("commitment hash", commitmentHash),
+		optionalID("subject hash", subjectHash),
+		maxLength("timestamp", timestamp, maxIDLength),
+		maxLength("metadata", metadataJSON, maxJSONLength),
+	); err != nil {
+		return err
+	}
+
+	election, err := requireElectionOpen(ctx, electionID)
 	if err != nil {
 		return err
 	}
-	if exists != nil {
-		return fmt.Errorf("ballot commitment already exists")
+	if err := election.checkCommitmentHash(commitmentHash); err != nil {
+		return err
+	}
+
+	key, err := ballotKey(ctx, electionID, commitmentHash)
+	if err != nil {
+		return err
 	}
 
 	// Parse metadata
 	var metadata map[string]any
 	if metadataJSON != "" {
 		if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
+			return withCode(ErrCodeInvalidArgument, err)
+		}
+	}
+
+	if err := election.checkBallotMetadata(metadata); err != nil {
+		return err
+	}
+
+	// Private ballots keep their metadata out of the public record
+	publicMetadata := metadata
+	if collection != "" {
+		publicMetadata = nil
+	}
+
+	// Check if commitment already exists (prevent double submission)
+	exists, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return err
+	}
+	if exists != nil {
+		var stored BallotCommitment
+		if err := unmarshalState(exists, &stored); err != nil {
+			return err
+		}
+		if election.DuplicatePolicy == DuplicateIdempotent && stored.sameSubmission(ballotID, subjectHash, timestamp, publicMetadata) {
+			return nil
+		}
+		return codedErrorf(ErrCodeDuplicateCommitment, "ballot commitment already exists")
+	}
+
+	// Reject a second, different commitment for the same ballot
+	if err := requireBallotIDUnused(ctx, electionID, ballotID); err != nil {
+		return err
+	}
+	if election.MaxTotalVotes > 0 {
+		ballots, err := readCounter(ctx, ballotCountKey(electionID))
+		if err != nil {
+			return err
+		}
+		if err := election.checkCapacity(ballots); err != nil {
 			return err
 		}
 	}
@@ -124,96 +560,187 @@ func (c *BallotContract) SubmitBallotCommitment(
 
 	// Create ballot commitment record
 	commitment := BallotCommitment{
-		ElectionID:     electionID,
-		BallotID:       ballotID,
-		CommitmentHash: commitmentHash,
-		Timestamp:      timestamp,
-		Metadata:       metadata,
-		TxID:           txID,
+		ElectionID:        electionID,
+		BallotID:          ballotID,
+		CommitmentHash:    commitmentHash,
+		SubjectHash:       subjectHash,
+		Timestamp:         timestamp,
+		Metadata:          publicMetadata,
+		TxID:              txID,
+		PrivateCollection: collection,
+	}
+
+	// Record the ordering timestamp and flag client clocks that disagree with it
+	if err := stampBallot(ctx, election, &commitment); err != nil {
+		return err
+	}
+	if commitment.SubjectHash != "" {
+		if commitment.PrevCommitmentHash, err = ballotChainHead(ctx, electionID, subjectHash); err != nil {
+			return err
+		}
+	}
+
+	if collection != "" {
+		private := commitment
+		private.Metadata = metadata
+		if err := putPrivateBallot(ctx, collection, key, &private); err != nil {
+			return err
+		}
 	}
 
 	// Serialize and store
-	bytes, err := json.Marshal(commitment)
-	if err != nil {
+	if err := putBallot(ctx, key, &commitment); err != nil {
+		return err
+	}
+
+	// Index the hash so GetBallotCommitment can resolve the election directly
+	if err := indexBallot(ctx, electionID, commitmentHash); err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(ballotIDKey(electionID, ballotID), []byte(commitmentHash)); err != nil {
+		return err
+	}
+	if err := indexBallotSubject(ctx, &commitment); err != nil {
+		return err
+	}
+	if err := advanceBallotChain(ctx, &commitment); err != nil {
+		return err
+	}
+	if err := addToCounter(ctx, ballotCountKey(electionID), 1); err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(key, bytes)
+	return emitSubmissionEvent(ctx, EventBallotCommitted, electionID, commitmentHash)
 }
 
-// GetBallotCommitment retrieves a ballot commitment by its hash.
+// GetBallotCommitment retrieves a ballot commitment by its hash. The hash index
+// resolves the election first, so this costs two point reads; callers that know
+// the election should use GetBallotCommitmentForElection, which needs one. If
+// the same hash was submitted in several elections, the first wins.
 func (c *BallotContract) GetBallotCommitment(
 	ctx contractapi.TransactionContextInterface,
 	commitmentHash string,
 ) (*BallotCommitment, error) {
-	// Search across all elections for this commitment
-	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey("ballot", []string{})
+	electionID, err := ctx.GetStub().GetState(ballotIndexKey(commitmentHash))
 	if err != nil {
 		return nil, err
 	}
-	defer iterator.Close()
-
-	for iterator.HasNext() {
-		record, err := iterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var commitment BallotCommitment
-		if err := json.Unmarshal(record.Value, &commitment); err != nil {
-			continue
-		}
-
-		if commitment.CommitmentHash == commitmentHash {
-			return &commitment, nil
-		}
+	if electionID == nil {
+		return nil, codedErrorf(ErrCodeNotFound, "ballot commitment not found")
 	}
 
-	return nil, fmt.Errorf("ballot commitment not found")
+	return getBallot(ctx, string(electionID), commitmentHash)
 }
 
 // AnchorAuditLogs anchors a Merkle root of audit logs to the blockchain.
+// Anchors form a hash chain per election: previousRoot must equal the root
+// most recently anchored for electionID, or be empty for the first anchor, so
+// a missing anchor breaks the chain detectably. An empty electionID anchors to
+// the chain of logs not tied to any election.
 func (c *BallotContract) AnchorAuditLogs(
 	ctx contractapi.TransactionContextInterface,
-	merkleRoot, timestamp string,
+	electionID, merkleRoot, previousRoot, timestamp string,
 	batchSize int,
 	metadataJSON string,
 ) error {
-	key := fmt.Sprintf("audit:%s", merkleRoot)
+	if err := validateInputs(
+		optionalKeyID("election ID", electionID),
+		requireDigest("merkle root", merkleRoot, HashSHA256),
+		maxLength("previous root", previousRoot, maxHashLength),
+		maxLength("timestamp", timestamp, maxIDLength),
+		maxLength("metadata", metadataJSON, maxJSONLength),
+	); err != nil {
+		return err
+	}
+
+	key := auditKey(merkleRoot)
+
+	// Check the root is new and extends the election's chain
+	exists, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return err
+	}
+	if exists != nil {
+		return codedErrorf(ErrCodeAlreadyExists, "merkle root already anchored")
+	}
+	latest, err := ctx.GetStub().GetState(latestAuditRootKey(electionID))
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(previousRoot, string(latest)) {
+		return codedErrorf(ErrCodeAuditChainBroken, "audit chain broken: previous root must be %q", string(latest))
+	}
 
 	// Parse metadata
 	var metadata map[string]any
 	if metadataJSON != "" {
 		if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
-			return err
+			return withCode(ErrCodeInvalidArgument, err)
 		}
 	}
 
 	// Create audit log entry
 	entry := AuditLogEntry{
-		MerkleRoot: merkleRoot,
-		Timestamp:  timestamp,
-		BatchSize:  batchSize,
-		Metadata:   metadata,
+		ElectionID:   electionID,
+		MerkleRoot:   merkleRoot,
+		PreviousRoot: previousRoot,
+		Timestamp:    timestamp,
+		BatchSize:    batchSize,
+		Metadata:     metadata,
+		TxID:         ctx.GetStub().GetTxID(),
 	}
 
 	// Serialize and store
-	bytes, err := json.Marshal(entry)
+	bytes, err := marshalState(entry)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(key, bytes)
+	if err := ctx.GetStub().PutState(key, bytes); err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(latestAuditRootKey(electionID), []byte(merkleRoot))
 }
 
-// CertifyResults anchors certified election results to the blockchain.
+// CertifyResults anchors certified election results to the blockchain and
+// moves a CLOSED election to CERTIFIED. resultsHash must equal the canonical
+// hash of the tally snapshot taken when the election closed (see
+// ComputeResultsHash), and the results record references that snapshot's
+// transaction. The caller must belong to an allowed certifier organization
+// (see SetAllowedCertifiers), or be an election official while no certifier
+// list has been set. The results key is then bound to the
+// certifier organization's endorsement. Results are certified whether or not
+// the turnout reached the election's QuorumThreshold; the record's QuorumMet
+// says which. Emits a "ResultsCertified" event.
 func (c *BallotContract) CertifyResults(
 	ctx contractapi.TransactionContextInterface,
 	electionID, resultsHash string,
 	totalVotes int,
 	certifiedAt, certifierID, metadataJSON string,
 ) error {
-	key := fmt.Sprintf("results:%s", electionID)
+	if err := validateInputs(
+		requireHash("results hash", resultsHash),
+		maxLength("certified at", certifiedAt, maxIDLength),
+		maxLength("certifier ID", certifierID, maxIDLength),
+		maxLength("metadata", metadataJSON, maxJSONLength),
+	); err != nil {
+		return err
+	}
+
+	if err := requireCertifier(ctx); err != nil {
+		return err
+	}
+
+	key := resultsKey(electionID)
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if election.Status == StatusCertified {
+		return codedErrorf(ErrCodeInvalidStatus, "election %s is already CERTIFIED", electionID)
+	}
 
 	// Check if already certified
 	exists, err := ctx.GetStub().GetState(key)
@@ -221,59 +748,186 @@ func (c *BallotContract) CertifyResults(
 		return err
 	}
 	if exists != nil {
-		return fmt.Errorf("election results already certified")
+		return codedErrorf(ErrCodeAlreadyExists, "election results already certified")
+	}
+
+	// Verify the supplied hash reflects the tally frozen at close
+	snapshot, err := getTallySnapshot(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	expectedHash, err := canonicalResultsHash(&snapshot.Tally)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(resultsHash, expectedHash) {
+		return codedErrorf(ErrCodeResultsMismatch, "results hash mismatch: tally snapshot hashes to %s", expectedHash)
+	}
+
+	turnout, err := c.GetTurnout(ctx, electionID)
+	if err != nil {
+		return err
 	}
 
 	// Parse metadata
 	var metadata map[string]any
 	if metadataJSON != "" {
 		if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
-			return err
+			return withCode(ErrCodeInvalidArgument, err)
 		}
 	}
 
 	// Create results record
 	results := ElectionResult{
+		ElectionID:   electionID,
+		ResultsHash:  resultsHash,
+		TotalVotes:   totalVotes,
+		CertifiedAt:  certifiedAt,
+		CertifierID:  certifierID,
+		Metadata:     metadata,
+		SnapshotTxID: snapshot.TxID,
+		Winner:       snapshot.Winner,
+		Ties:         snapshot.Ties,
+		Abstentions:  snapshot.Abstentions,
+
+		QuorumThreshold: election.QuorumThreshold,
+		TurnoutPercent:  turnout.TurnoutPercent,
+		QuorumMet:       turnout.TurnoutPercent >= election.QuorumThreshold,
+	}
+
+	// Serialize and store
+	bytes, err := marshalState(results)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.GetStub().PutState(key, bytes); err != nil {
+		return err
+	}
+
+	// Require the certifier org's endorsement for any future write
+	if err := lockResults(ctx, key); err != nil {
+		return err
+	}
+
+	// Lock the election against further submissions
+	if err := transitionElection(ctx, electionID, StatusCertified, "", StatusClosed); err != nil {
+		return err
+	}
+	if err := logEvent(ctx, electionID, EventResultsCertified, resultsHash); err != nil {
+		return err
+	}
+
+	return emitEvent(ctx, EventResultsCertified, ResultsCertifiedEvent{
 		ElectionID:  electionID,
 		ResultsHash: resultsHash,
 		TotalVotes:  totalVotes,
 		CertifiedAt: certifiedAt,
 		CertifierID: certifierID,
-		Metadata:    metadata,
+		QuorumMet:   results.QuorumMet,
+	})
+}
+
+// GetReceipt returns a vote receipt for the provided commitment. When the
+// commitment was recorded in more than one election, it returns the first of
+// the receipts GetReceipts returns.
+func (c *BallotContract) GetReceipt(ctx contractapi.TransactionContextInterface, commitmentHash string) (*VoteCommitment, error) {
+	receipts, err := c.GetReceipts(ctx, commitmentHash)
+	if err != nil {
+		return nil, err
 	}
+	return receipts[0], nil
+}
 
-	// Serialize and store
-	bytes, err := json.Marshal(results)
+// GetReceipts returns a vote receipt for every vote recorded under the
+// provided commitment, ordered by election ID.
+func (c *BallotContract) GetReceipts(ctx contractapi.TransactionContextInterface, commitmentHash string) ([]*VoteCommitment, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteCommitmentIndex, []string{commitmentHash})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer iterator.Close()
 
-	return ctx.GetStub().PutState(key, bytes)
+	receipts := []*VoteCommitment{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(record.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(attributes) != 2 {
+			continue
+		}
+
+		key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{attributes[1], commitmentHash})
+		if err != nil {
+			return nil, err
+		}
+		bytes, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, err
+		}
+		if bytes == nil {
+			continue
+		}
+
+		var commitment VoteCommitment
+		if err := unmarshalState(bytes, &commitment); err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, &commitment)
+	}
+	if len(receipts) == 0 {
+		return nil, codedErrorf(ErrCodeNotFound, "commitment not found")
+	}
+	return receipts, nil
 }
 
-// GetReceipt returns a vote receipt for the provided commitment.
-func (c *BallotContract) GetReceipt(ctx contractapi.TransactionContextInterface, commitmentHash string) (*VoteCommitment, error) {
-	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey("vote", []string{"", commitmentHash})
+// findVote resolves a commitment hash through the commitment index and
+// returns the vote together with the key it is stored under.
+func findVote(ctx contractapi.TransactionContextInterface, commitmentHash string) (string, *VoteCommitment, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteCommitmentIndex, []string{commitmentHash})
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 	defer iterator.Close()
 
 	if !iterator.HasNext() {
-		return nil, fmt.Errorf("commitment not found")
+		return "", nil, codedErrorf(ErrCodeNotFound, "commitment not found")
 	}
 
 	record, err := iterator.Next()
 	if err != nil {
-		return nil, err
+		return "", nil, err
+	}
+
+	_, attributes, err := ctx.GetStub().SplitCompositeKey(record.Key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{attributes[1], commitmentHash})
+	if err != nil {
+		return "", nil, err
+	}
+
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", nil, err
+	}
+	if bytes == nil {
+		return "", nil, codedErrorf(ErrCodeNotFound, "commitment not found")
 	}
 
 	var commitment VoteCommitment
-	if err := json.Unmarshal(record.Value, &commitment); err != nil {
-		return nil, err
+	if err := unmarshalState(bytes, &commitment); err != nil {
+		return "", nil, err
 	}
 
-	return &commitment, nil
+	return key, &commitment, nil
 }
 
 func main() {
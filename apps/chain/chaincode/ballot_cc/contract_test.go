@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+)
+
+func TestGetReceiptFindsCastVote(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+
+	var receipt *VoteCommitment
+	env.mustInvoke(func() (err error) {
+		receipt, err = env.contract.GetReceipt(env.ctx, testHash(1))
+		return err
+	})
+	if receipt.ElectionID != "e1" || receipt.CommitmentHash != testHash(1) || receipt.OptionID != "yes" {
+		t.Fatalf("got receipt %+v", receipt)
+	}
+}
+
+func TestGetReceiptUnknownCommitment(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+
+	err := env.invoke(func() error {
+		_, err := env.contract.GetReceipt(env.ctx, testHash(2))
+		return err
+	})
+	wantCode(t, err, ErrCodeNotFound)
+}
+
+func TestCastVoteRejectsSecondVoteBySubject(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+
+	err := env.invoke(func() error {
+		return env.contract.CastVote(env.ctx, "e1", "subject1", testHash(2), "no", "{}")
+	})
+	wantCode(t, err, ErrCodeAlreadyVoted)
+
+	var count int
+	env.mustInvoke(func() (err error) {
+		count, err = env.contract.GetVoteCount(env.ctx, "e1")
+		return err
+	})
+	if count != 1 {
+		t.Fatalf("got vote count %d, want 1", count)
+	}
+}
+
+func TestCastVoteSubjectVotesInEachElection(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.openElection("e2", `{"title":"Budget","options":["yes","no"],"allowUnregistered":true}`)
+
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	env.castVote("e2", "subject1", testHash(2), "no")
+}
+
+// readCountingStub counts the point reads and range scans made through it.
+type readCountingStub struct {
+	*shimtest.MockStub
+	reads, scans int
+}
+
+func (s *readCountingStub) GetState(key string) ([]byte, error) {
+	s.reads++
+	return s.MockStub.GetState(key)
+}
+
+func (s *readCountingStub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	s.scans++
+	return s.MockStub.GetStateByRange(startKey, endKey)
+}
+
+func (s *readCountingStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	s.scans++
+	return s.MockStub.GetStateByPartialCompositeKey(objectType, attributes)
+}
+
+func TestGetBallotCommitmentUsesPointReads(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"]}`)
+	env.openElection("e2", `{"title":"Budget","options":["yes","no"]}`)
+	for i := 1; i <= 20; i++ {
+		electionID := "e1"
+		if i%2 == 0 {
+			electionID = "e2"
+		}
+		env.mustInvoke(func() error {
+			return env.contract.SubmitBallotCommitment(env.ctx, electionID, fmt.Sprintf("ballot%d", i), testHash(i), "", "")
+		})
+	}
+
+	counter := &readCountingStub{MockStub: env.stub}
+	env.ctx.SetStub(counter)
+	var ballot *BallotCommitment
+	env.mustInvoke(func() (err error) {
+		ballot, err = env.contract.GetBallotCommitment(env.ctx, testHash(14))
+		return err
+	})
+	if ballot.ElectionID != "e2" || ballot.CommitmentHash != testHash(14) {
+		t.Fatalf("got ballot %+v", ballot)
+	}
+	if counter.scans != 0 || counter.reads != 2 {
+		t.Fatalf("got %d reads and %d scans, want 2 reads and no scans", counter.reads, counter.scans)
+	}
+}
+
+func TestGetBallotCommitmentNotFound(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"]}`)
+
+	err := env.invoke(func() error {
+		_, err := env.contract.GetBallotCommitment(env.ctx, testHash(1))
+		return err
+	})
+	wantCode(t, err, ErrCodeNotFound)
+}
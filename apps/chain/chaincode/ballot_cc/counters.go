@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Counters are stored as decimal strings under plain keys. Every writer of a
+// counter reads and rewrites the same key, so concurrent transactions touching
+// it in the same block fail MVCC validation and must be resubmitted. That is
+// acceptable at per-voter submission rates but makes the counter key a
+// contention hotspot during peak voting.
+//
+// The vote count, written by every first vote, is therefore sharded: each
+// transaction adds to one of voteCountShards shard keys, chosen from its
+// transaction ID, and readers sum the shards. Two votes conflict only when
+// they land on the same shard in the same block. More shards mean fewer
+// conflicts but more point reads for every count; a vote in an election with
+// a MaxTotalVotes cap reads all of them and so conflicts with any concurrent
+// vote, as before. A shard may hold a negative value after an invalidation;
+// only the sum is meaningful.
+
+// voteCountShards is the number of vote count shards per election. Changing
+// it strands the counts held by the shards it drops.
+const voteCountShards = 16
+
+// voteCountKey is the unsharded vote count written before sharding. It is
+// still included in the sum but no longer written.
+func voteCountKey(electionID string) string {
+	return fmt.Sprintf("count:%s", electionID)
+}
+
+func voteCountShardKey(electionID string, shard int) string {
+	return fmt.Sprintf("count:%s:shard%d", electionID, shard)
+}
+
+// voteCountShard returns the shard the current transaction writes. It is
+// derived from the transaction ID so every endorser picks the same one.
+func voteCountShard(ctx contractapi.TransactionContextInterface) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(ctx.GetStub().GetTxID()))
+	return int(hash.Sum32() % voteCountShards)
+}
+
+// readVoteCount returns the number of subjects who have voted in an election,
+// summed over the vote count shards.
+func readVoteCount(ctx contractapi.TransactionContextInterface, electionID string) (int, error) {
+	total, err := readCounter(ctx, voteCountKey(electionID))
+	if err != nil {
+		return 0, err
+	}
+	for shard := 0; shard < voteCountShards; shard++ {
+		value, err := readCounter(ctx, voteCountShardKey(electionID, shard))
+		if err != nil {
+			return 0, err
+		}
+		total += value
+	}
+	return total, nil
+}
+
+// addToVoteCount adds delta to the current transaction's vote count shard.
+func addToVoteCount(ctx contractapi.TransactionContextInterface, electionID string, delta int) error {
+	return addToCounter(ctx, voteCountShardKey(electionID, voteCountShard(ctx)), delta)
+}
+
+func registeredCountKey(electionID string) string {
+	return fmt.Sprintf("registered:%s", electionID)
+}
+
+func ballotCountKey(electionID string) string {
+	return fmt.Sprintf("ballots:%s", electionID)
+}
+
+func spoiledBallotCountKey(electionID string) string {
+	return fmt.Sprintf("spoiledballots:%s", electionID)
+}
+
+// BallotCount is the number of ballot commitments recorded in an election.
+type BallotCount struct {
+	Total   int `json:"total"`
+	Spoiled int `json:"spoiled"`
+	Valid   int `json:"valid"`
+}
+
+// Turnout is the share of registered subjects who have voted in an election.
+type Turnout struct {
+	Registered     int     `json:"registered"`
+	Voted          int     `json:"voted"`
+	TurnoutPercent float64 `json:"turnoutPercent"`
+}
+
+// readCounter returns the value stored under key, or zero if it is unset.
+func readCounter(ctx contractapi.TransactionContextInterface, key string) (int, error) {
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return 0, err
+	}
+	if bytes == nil {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(string(bytes))
+	if err != nil {
+		return 0, fmt.Errorf("corrupt counter %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// addToCounter adds delta to the counter stored under key.
+func addToCounter(ctx contractapi.TransactionContextInterface, key string, delta int) error {
+	value, err := readCounter(ctx, key)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, []byte(strconv.Itoa(value+delta)))
+}
+
+// checkCapacity returns an error if an election with count votes or ballots
+// recorded has reached its MaxTotalVotes.
+func (e *Election) checkCapacity(count int) error {
+	if e.MaxTotalVotes > 0 && count >= e.MaxTotalVotes {
+		return codedErrorf(ErrCodeCapacityReached, "capacity reached: election %s accepts at most %d", e.ElectionID, e.MaxTotalVotes)
+	}
+	return nil
+}
+
+// GetVoteCount returns the number of subjects who have voted in an election
+// without scanning the votes themselves. This equals the number of votes
+// except in multi-contest elections, where a subject votes once per contest.
+func (c *BallotContract) GetVoteCount(ctx contractapi.TransactionContextInterface, electionID string) (int, error) {
+	return readVoteCount(ctx, electionID)
+}
+
+// GetBallotCommitmentCount returns the number of ballot commitments recorded
+// in an election and how many of them were spoiled, read from counters rather
+// than by scanning. Ballots recorded before the counters were introduced are
+// not included.
+func (c *BallotContract) GetBallotCommitmentCount(ctx contractapi.TransactionContextInterface, electionID string) (*BallotCount, error) {
+	if _, err := getElection(ctx, electionID); err != nil {
+		return nil, err
+	}
+
+	total, err := readCounter(ctx, ballotCountKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	spoiled, err := readCounter(ctx, spoiledBallotCountKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	return &BallotCount{Total: total, Spoiled: spoiled, Valid: total - spoiled}, nil
+}
+
+// GetTurnout returns the number of registered subjects, the number of votes
+// cast and the turnout as a percentage, read from counters rather than by
+// scanning. Turnout is 0 when nobody is registered. Subjects registered before
+// the registered count was introduced are not included.
+func (c *BallotContract) GetTurnout(ctx contractapi.TransactionContextInterface, electionID string) (*Turnout, error) {
+	if _, err := getElection(ctx, electionID); err != nil {
+		return nil, err
+	}
+
+	registered, err := readCounter(ctx, registeredCountKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	voted, err := readVoteCount(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	turnout := &Turnout{Registered: registered, Voted: voted}
+	if registered > 0 {
+		turnout.TurnoutPercent = float64(voted) / float64(registered) * 100
+	}
+	return turnout, nil
+}
+
+// ElectionStats gathers the figures a dashboard shows for an election, read
+// in one query so they come from the same ledger state.
+type ElectionStats struct {
+	ElectionID     string         `json:"electionId"`
+	Status         ElectionStatus `json:"status"`
+	Registered     int            `json:"registered"`
+	VotesCast      int            `json:"votesCast"`
+	TurnoutPercent float64        `json:"turnoutPercent"`
+	Ballots        int            `json:"ballots"`
+	SpoiledBallots int            `json:"spoiledBallots"`
+	Counts         map[string]int `json:"counts"`
+	Abstentions    int            `json:"abstentions"`
+	FromSnapshot   bool           `json:"fromSnapshot"`
+}
+
+// GetElectionStats returns an election's status, registered and voted counts,
+// turnout, ballot and spoiled ballot counts and per-option tally. The counts
+// come from counters as in GetTurnout and GetBallotCommitmentCount; the tally
+// is the snapshot taken at closing once the election is closed
+// (FromSnapshot), and is computed from the votes before then.
+func (c *BallotContract) GetElectionStats(ctx contractapi.TransactionContextInterface, electionID string) (*ElectionStats, error) {
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	turnout, err := c.GetTurnout(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	ballots, err := c.GetBallotCommitmentCount(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &ElectionStats{
+		ElectionID:     electionID,
+		Status:         election.Status,
+		Registered:     turnout.Registered,
+		VotesCast:      turnout.Voted,
+		TurnoutPercent: turnout.TurnoutPercent,
+		Ballots:        ballots.Total,
+		SpoiledBallots: ballots.Spoiled,
+	}
+
+	var tally *Tally
+	snapshot, err := ctx.GetStub().GetState(tallySnapshotKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		var frozen TallySnapshot
+		if err := unmarshalState(snapshot, &frozen); err != nil {
+			return nil, err
+		}
+		tally = &frozen.Tally
+		stats.FromSnapshot = true
+	} else if tally, err = computeTally(ctx, electionID); err != nil {
+		return nil, err
+	}
+	stats.Counts = tally.Counts
+	stats.Abstentions = tally.Abstentions
+	return stats, nil
+}
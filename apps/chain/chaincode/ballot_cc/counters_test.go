@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// scanVoteCount counts an election's vote records with a full scan.
+func scanVoteCount(env *testEnv, electionID string) int {
+	env.t.Helper()
+	count := 0
+	env.mustInvoke(func() error {
+		iterator, err := env.ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{electionID})
+		if err != nil {
+			return err
+		}
+		defer iterator.Close()
+		for iterator.HasNext() {
+			if _, err := iterator.Next(); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+func TestGetVoteCountMatchesScan(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.openElection("e2", `{"title":"Budget","options":["yes","no"],"allowUnregistered":true}`)
+	for i := 1; i <= 25; i++ {
+		env.castVote("e1", fmt.Sprintf("subject%d", i), testHash(i), "yes")
+	}
+	env.castVote("e2", "subject1", testHash(100), "no")
+
+	var count int
+	env.mustInvoke(func() (err error) {
+		count, err = env.contract.GetVoteCount(env.ctx, "e1")
+		return err
+	})
+	if scanned := scanVoteCount(env, "e1"); count != scanned || count != 25 {
+		t.Fatalf("got vote count %d, scan found %d votes, want 25", count, scanned)
+	}
+}
+
+func TestGetVoteCountIncludesUnshardedCount(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.mustInvoke(func() error {
+		return env.ctx.GetStub().PutState(voteCountKey("e1"), []byte("3"))
+	})
+	env.castVote("e1", "subject1", testHash(1), "yes")
+
+	var count int
+	env.mustInvoke(func() (err error) {
+		count, err = env.contract.GetVoteCount(env.ctx, "e1")
+		return err
+	})
+	if count != 4 {
+		t.Fatalf("got vote count %d, want 4", count)
+	}
+}
+
+func TestGetBallotCommitmentCount(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"]}`)
+	env.openElection("e2", `{"title":"Budget","options":["yes","no"]}`)
+	const ballots = 12
+	for i := 1; i <= ballots; i++ {
+		env.mustInvoke(func() error {
+			return env.contract.SubmitBallotCommitment(env.ctx, "e1", fmt.Sprintf("ballot%d", i), testHash(i), "", "")
+		})
+	}
+	env.mustInvoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e2", "ballot1", testHash(100), "", "")
+	})
+	for i := 1; i <= 3; i++ {
+		env.mustInvoke(func() error {
+			return env.contract.SpoilBallot(env.ctx, "e1", testHash(i), "torn")
+		})
+	}
+
+	counter := &readCountingStub{MockStub: env.stub}
+	env.ctx.SetStub(counter)
+	var count *BallotCount
+	env.mustInvoke(func() (err error) {
+		count, err = env.contract.GetBallotCommitmentCount(env.ctx, "e1")
+		return err
+	})
+	if want := (BallotCount{Total: ballots, Spoiled: 3, Valid: ballots - 3}); *count != want {
+		t.Fatalf("got %+v, want %+v", *count, want)
+	}
+	if counter.scans != 0 {
+		t.Fatalf("counting ballots made %d range scans", counter.scans)
+	}
+}
+
+func TestMaxTotalVotesRejectsVoteOverCapacity(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true,"maxTotalVotes":3}`)
+	for i := 1; i <= 3; i++ {
+		env.castVote("e1", fmt.Sprintf("subject%d", i), testHash(i), "yes")
+	}
+
+	err := env.invoke(func() error {
+		return env.contract.CastVote(env.ctx, "e1", "subject4", testHash(4), "yes", "{}")
+	})
+	wantCode(t, err, ErrCodeCapacityReached)
+}
+
+func TestMaxTotalVotesRejectsBallotOverCapacity(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"maxTotalVotes":2}`)
+	for i := 1; i <= 2; i++ {
+		env.mustInvoke(func() error {
+			return env.contract.SubmitBallotCommitment(env.ctx, "e1", fmt.Sprintf("ballot%d", i), testHash(i), "", "")
+		})
+	}
+
+	err := env.invoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot3", testHash(3), "", "")
+	})
+	wantCode(t, err, ErrCodeCapacityReached)
+}
+
+func TestZeroMaxTotalVotesIsUnlimited(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true,"maxTotalVotes":0}`)
+	for i := 1; i <= 20; i++ {
+		env.castVote("e1", fmt.Sprintf("subject%d", i), testHash(i), "yes")
+	}
+}
+
+// TestShardedVoteCountUnderConcurrentIncrements simulates concurrent votes
+// block by block: of the transactions endorsed against the same state, only
+// the first to write each shard commits and the others fail MVCC validation
+// and are resubmitted in the next block.
+func TestShardedVoteCountUnderConcurrentIncrements(t *testing.T) {
+	env := newTestEnv(t)
+	const votes = 64
+	pending := make([]string, votes)
+	for i := range pending {
+		pending[i] = fmt.Sprintf("vote%d", i)
+	}
+
+	blocks := 0
+	for len(pending) > 0 {
+		blocks++
+		written := map[int]bool{}
+		retry := []string{}
+		for _, txID := range pending {
+			env.stub.MockTransactionStart(txID)
+			shard := voteCountShard(env.ctx)
+			if written[shard] {
+				retry = append(retry, txID)
+			} else {
+				written[shard] = true
+				if err := addToVoteCount(env.ctx, "e1", 1); err != nil {
+					t.Fatal(err)
+				}
+			}
+			env.stub.MockTransactionEnd(txID)
+		}
+		if blocks == 1 && len(written) < 2 {
+			t.Fatalf("the first block committed %d increments, want several shards written", len(written))
+		}
+		pending = retry
+	}
+	if blocks >= votes {
+		t.Fatalf("committing %d increments took %d blocks, no better than a single counter", votes, blocks)
+	}
+
+	var count int
+	env.mustInvoke(func() (err error) {
+		count, err = readVoteCount(env.ctx, "e1")
+		return err
+	})
+	if count != votes {
+		t.Fatalf("got vote count %d, want %d", count, votes)
+	}
+}
+
+func TestVoteCountShardIsDeterministic(t *testing.T) {
+	env := newTestEnv(t)
+	shards := map[int]bool{}
+	for i := 0; i < 100; i++ {
+		txID := fmt.Sprintf("tx-%d", i)
+		env.stub.MockTransactionStart(txID)
+		shard := voteCountShard(env.ctx)
+		if again := voteCountShard(env.ctx); again != shard {
+			t.Fatalf("transaction %s picked shards %d and %d", txID, shard, again)
+		}
+		if shard < 0 || shard >= voteCountShards {
+			t.Fatalf("transaction %s picked shard %d of %d", txID, shard, voteCountShards)
+		}
+		shards[shard] = true
+		env.stub.MockTransactionEnd(txID)
+	}
+	if len(shards) < voteCountShards/2 {
+		t.Fatalf("100 transactions used only %d of %d shards", len(shards), voteCountShards)
+	}
+}
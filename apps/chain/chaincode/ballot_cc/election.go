@@ -0,0 +1,965 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ElectionStatus is the lifecycle state of an election.
+type ElectionStatus string
+
+// Election lifecycle states. Subjects may register while DRAFT or
+// REGISTRATION; submissions are only accepted while OPEN. An ARCHIVED
+// election is CERTIFIED with its vote and ballot records purged.
+const (
+	StatusDraft        ElectionStatus = "DRAFT"
+	StatusRegistration ElectionStatus = "REGISTRATION"
+	StatusOpen         ElectionStatus = "OPEN"
+	StatusPaused       ElectionStatus = "PAUSED"
+	StatusClosed       ElectionStatus = "CLOSED"
+	StatusCertified    ElectionStatus = "CERTIFIED"
+	StatusArchived     ElectionStatus = "ARCHIVED"
+)
+
+// DuplicatePolicy controls how SubmitBallotCommitment treats a commitment
+// that is already recorded.
+type DuplicatePolicy string
+
+// Duplicate submission policies. REJECT is the default.
+const (
+	DuplicateReject     DuplicatePolicy = "REJECT"
+	DuplicateIdempotent DuplicatePolicy = "IDEMPOTENT"
+)
+
+// Election represents the on-chain state of an election.
+type Election struct {
+	Record
+
+	ElectionID string         `json:"electionId"`
+	Title      string         `json:"title"`
+	Status     ElectionStatus `json:"status"`
+	Options    []string       `json:"options"`
+
+	// Contests are the races of a multi-contest election. When set, every
+	// vote names a contest and Options is unused.
+	Contests []Contest `json:"contests,omitempty"`
+
+	// MaxClockSkewSeconds is how far a client-supplied ballot timestamp may
+	// differ from the transaction timestamp before the ballot is flagged.
+	// Zero uses defaultMaxClockSkew.
+	MaxClockSkewSeconds int `json:"maxClockSkewSeconds,omitempty"`
+
+	// RegistrationOpensAt and RegistrationClosesAt bound when subjects may
+	// register, as RFC3339 timestamps. An empty bound is unrestricted.
+	RegistrationOpensAt  string `json:"registrationOpensAt,omitempty"`
+	RegistrationClosesAt string `json:"registrationClosesAt,omitempty"`
+
+	// DuplicatePolicy is REJECT (or empty) to fail resubmitted ballot
+	// commitments, or IDEMPOTENT to accept identical retries as no-ops.
+	DuplicatePolicy DuplicatePolicy `json:"duplicatePolicy,omitempty"`
+
+	// RevealNotBefore is the RFC3339 time before which CombineShares refuses
+	// to combine trustee decryption shares. Empty means no embargo.
+	RevealNotBefore string `json:"revealNotBefore,omitempty"`
+
+	// Trustees maps the ID of each trustee of a threshold-encrypted tally to
+	// the client identity ID, as returned by the client identity's GetID, that
+	// must submit the trustee's decryption share.
+	Trustees map[string]string `json:"trustees,omitempty"`
+
+	// TrusteeThreshold is how many trustees' decryption shares CombineShares
+	// requires. It must be set, and at most len(Trustees), when Trustees is.
+	TrusteeThreshold int `json:"trusteeThreshold,omitempty"`
+
+	// AllowUnregistered lets subjects vote without registering first, for
+	// open-registration pilots. By default votes from unregistered subjects
+	// are rejected.
+	AllowUnregistered bool `json:"allowUnregistered,omitempty"`
+
+	// RequireVoterSignatures rejects votes not cast with CastSignedVote.
+	RequireVoterSignatures bool `json:"requireVoterSignatures,omitempty"`
+
+	// MaxVotesPerOption flags votes as Anomalous once an option has received
+	// more than this many votes. Zero disables the check.
+	MaxVotesPerOption int `json:"maxVotesPerOption,omitempty"`
+
+	// MaxTotalVotes caps how many subjects may vote and, separately, how many
+	// ballot commitments may be submitted. Zero is unlimited.
+	MaxTotalVotes int `json:"maxTotalVotes,omitempty"`
+
+	// MinVoteDelaySeconds is how long after registering a subject must wait
+	// before voting, to slow automated ballot stuffing. Zero disables it.
+	MinVoteDelaySeconds int `json:"minVoteDelaySeconds,omitempty"`
+
+	// QuorumThreshold is the turnout percentage, as reported by GetTurnout,
+	// the election needs for its results to be valid. CertifyResults records
+	// whether it was met. Zero disables the check.
+	QuorumThreshold float64 `json:"quorumThreshold,omitempty"`
+
+	// BallotMetadataSchema lists the metadata keys ballot commitments must or
+	// may carry and their types. Nil accepts any metadata.
+	BallotMetadataSchema map[string]MetadataField `json:"ballotMetadataSchema,omitempty"`
+
+	// Weighted makes each vote carry the weight registered for its subject
+	// with RegisterWeightedSubject, and TallyResults sum weights rather than
+	// count votes. TallyRankedResults ignores weights.
+	Weighted bool `json:"weighted,omitempty"`
+
+	// Sealed makes votes commit-then-reveal: they are cast without an option,
+	// whose hash with a salt is the commitment hash, and only counted once
+	// revealed with RevealVote.
+	Sealed bool `json:"sealed,omitempty"`
+
+	// RevealTTLSeconds is how long a sealed vote may stay unrevealed after
+	// being recorded before ExpireStaleCommitments expires it. Zero disables
+	// expiry.
+	RevealTTLSeconds int `json:"revealTtlSeconds,omitempty"`
+
+	// HashAlgorithm is the algorithm commitment hashes are expected to be
+	// digests of, which fixes their length. Empty means SHA-256.
+	HashAlgorithm string `json:"hashAlgorithm,omitempty"`
+
+	// ConfigHash is the canonical hash of the election's configuration. It is
+	// kept current while the configuration is editable and frozen once
+	// ConfigLocked is set, which happens no later than leaving DRAFT.
+	ConfigHash   string `json:"configHash"`
+	ConfigLocked bool   `json:"configLocked"`
+
+	// Transitions records when the election was created and each status it
+	// has since entered, oldest first. GetStatusHistory returns it.
+	Transitions []ElectionTransition `json:"transitions,omitempty"`
+}
+
+// ElectionTransition is the moment an election entered a status. From is
+// empty for the creation entry. Reason and ActorMSP are missing from
+// transitions recorded before they were introduced.
+type ElectionTransition struct {
+	From      ElectionStatus `json:"from,omitempty"`
+	Status    ElectionStatus `json:"status"`
+	Reason    string         `json:"reason,omitempty"`
+	ActorMSP  string         `json:"actorMsp,omitempty"`
+	Timestamp string         `json:"timestamp"`
+	TxID      string         `json:"txId"`
+}
+
+// recordTransition appends the election's move from the given status to its
+// current one to its transitions, with the reason given, the caller's MSP and
+// the transaction time.
+func (e *Election) recordTransition(ctx contractapi.TransactionContextInterface, from ElectionStatus, reason string) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	mspID, err := clientMSP(ctx)
+	if err != nil {
+		return err
+	}
+	e.Transitions = append(e.Transitions, ElectionTransition{
+		From:      from,
+		Status:    e.Status,
+		Reason:    reason,
+		ActorMSP:  mspID,
+		Timestamp: now.Format(time.RFC3339Nano),
+		TxID:      ctx.GetStub().GetTxID(),
+	})
+	return nil
+}
+
+// ElectionConfig is the configuration supplied to CreateElection. Together
+// with the election ID it is covered by the election's ConfigHash.
+type ElectionConfig struct {
+	Title                  string                   `json:"title"`
+	Options                []string                 `json:"options"`
+	MaxClockSkewSeconds    int                      `json:"maxClockSkewSeconds"`
+	RegistrationOpensAt    string                   `json:"registrationOpensAt"`
+	RegistrationClosesAt   string                   `json:"registrationClosesAt"`
+	DuplicatePolicy        DuplicatePolicy          `json:"duplicatePolicy"`
+	Contests               []Contest                `json:"contests,omitempty"`
+	RevealNotBefore        string                   `json:"revealNotBefore,omitempty"`
+	Trustees               map[string]string        `json:"trustees,omitempty"`
+	TrusteeThreshold       int                      `json:"trusteeThreshold,omitempty"`
+	AllowUnregistered      bool                     `json:"allowUnregistered,omitempty"`
+	RequireVoterSignatures bool                     `json:"requireVoterSignatures,omitempty"`
+	MaxVotesPerOption      int                      `json:"maxVotesPerOption,omitempty"`
+	BallotMetadataSchema   map[string]MetadataField `json:"ballotMetadataSchema,omitempty"`
+	Weighted               bool                     `json:"weighted,omitempty"`
+	HashAlgorithm          string                   `json:"hashAlgorithm,omitempty"`
+	MaxTotalVotes          int                      `json:"maxTotalVotes,omitempty"`
+	MinVoteDelaySeconds    int                      `json:"minVoteDelaySeconds,omitempty"`
+	QuorumThreshold        float64                  `json:"quorumThreshold,omitempty"`
+	Sealed                 bool                     `json:"sealed,omitempty"`
+	RevealTTLSeconds       int                      `json:"revealTtlSeconds,omitempty"`
+}
+
+// config returns the election's current configuration.
+func (e *Election) config() ElectionConfig {
+	return ElectionConfig{
+		Title:                  e.Title,
+		Options:                e.Options,
+		MaxClockSkewSeconds:    e.MaxClockSkewSeconds,
+		RegistrationOpensAt:    e.RegistrationOpensAt,
+		RegistrationClosesAt:   e.RegistrationClosesAt,
+		DuplicatePolicy:        e.DuplicatePolicy,
+		Contests:               e.Contests,
+		RevealNotBefore:        e.RevealNotBefore,
+		Trustees:               e.Trustees,
+		TrusteeThreshold:       e.TrusteeThreshold,
+		AllowUnregistered:      e.AllowUnregistered,
+		RequireVoterSignatures: e.RequireVoterSignatures,
+		MaxVotesPerOption:      e.MaxVotesPerOption,
+		BallotMetadataSchema:   e.BallotMetadataSchema,
+		Weighted:               e.Weighted,
+		HashAlgorithm:          e.HashAlgorithm,
+		MaxTotalVotes:          e.MaxTotalVotes,
+		MinVoteDelaySeconds:    e.MinVoteDelaySeconds,
+		QuorumThreshold:        e.QuorumThreshold,
+		Sealed:                 e.Sealed,
+		RevealTTLSeconds:       e.RevealTTLSeconds,
+	}
+}
+
+// configHash returns the hex SHA-256 of the election ID and configuration
+// serialized as one canonical JSON object (see marshalState), with keys sorted
+// at every level, e.g. {"allowUnregistered":false,...,"electionId":"e1",...}.
+func (e *Election) configHash() (string, error) {
+	bytes, err := marshalState(struct {
+		ElectionID string `json:"electionId"`
+		ElectionConfig
+	}{e.ElectionID, e.config()})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkRegistrationWindow returns an error if now is outside the election's
+// registration window.
+func (e *Election) checkRegistrationWindow(now time.Time) error {
+	if e.RegistrationOpensAt != "" {
+		opens, err := parseTimestamp(e.RegistrationOpensAt)
+		if err != nil {
+			return err
+		}
+		if now.Before(opens) {
+			return codedErrorf(ErrCodeRegistrationClosed, "registration for election %s opens at %s", e.ElectionID, e.RegistrationOpensAt)
+		}
+	}
+	if e.RegistrationClosesAt != "" {
+		closes, err := parseTimestamp(e.RegistrationClosesAt)
+		if err != nil {
+			return err
+		}
+		if !now.Before(closes) {
+			return codedErrorf(ErrCodeRegistrationClosed, "registration for election %s closed at %s", e.ElectionID, e.RegistrationClosesAt)
+		}
+	}
+	return nil
+}
+
+// validateRegistrationWindow checks that the optional RFC3339 bounds of a
+// registration window parse and are correctly ordered.
+func validateRegistrationWindow(opensAt, closesAt string) error {
+	var opens, closes time.Time
+	var err error
+	if opensAt != "" {
+		if opens, err = parseTimestamp(opensAt); err != nil {
+			return err
+		}
+	}
+	if closesAt != "" {
+		if closes, err = parseTimestamp(closesAt); err != nil {
+			return err
+		}
+	}
+	if opensAt != "" && closesAt != "" && !closes.After(opens) {
+		return fmt.Errorf("registration must close after it opens")
+	}
+	return nil
+}
+
+const defaultMaxClockSkew = 5 * time.Minute
+
+// maxClockSkew returns the election's clock skew tolerance.
+func (e *Election) maxClockSkew() time.Duration {
+	if e.MaxClockSkewSeconds <= 0 {
+		return defaultMaxClockSkew
+	}
+	return time.Duration(e.MaxClockSkewSeconds) * time.Second
+}
+
+// checkCommitmentHash checks that a commitment hash has the length of the
+// election's hash algorithm.
+func (e *Election) checkCommitmentHash(commitmentHash string) error {
+	algorithm := e.HashAlgorithm
+	if algorithm == "" {
+		algorithm = HashSHA256
+	}
+	return withCode(ErrCodeInvalidArgument, requireDigest("commitment hash", commitmentHash, algorithm))
+}
+
+// digest returns the hex digest of data under the election's hash algorithm.
+func (e *Election) digest(data string) string {
+	switch e.HashAlgorithm {
+	case HashSHA384:
+		sum := sha512.Sum384([]byte(data))
+		return hex.EncodeToString(sum[:])
+	case HashSHA512:
+		sum := sha512.Sum512([]byte(data))
+		return hex.EncodeToString(sum[:])
+	default:
+		sum := sha256.Sum256([]byte(data))
+		return hex.EncodeToString(sum[:])
+	}
+}
+
+// hasOption reports whether optionID is one of the election's configured options.
+func (e *Election) hasOption(optionID string) bool {
+	return containsOption(e.Options, optionID)
+}
+
+func containsOption(options []string, optionID string) bool {
+	for _, option := range options {
+		if option == optionID {
+			return true
+		}
+	}
+	return false
+}
+
+// electionKey is the plain key of an election record. ListElections scans the
+// "election:" prefix, so no other record type may use it.
+func electionKey(electionID string) string {
+	return fmt.Sprintf("election:%s", electionID)
+}
+
+// getElection reads an election record, failing if it does not exist.
+func getElection(ctx contractapi.TransactionContextInterface, electionID string) (*Election, error) {
+	bytes, err := ctx.GetStub().GetState(electionKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	if bytes == nil {
+		return nil, codedErrorf(ErrCodeNotFound, "election %s not found", electionID)
+	}
+
+	var election Election
+	if err := unmarshalState(bytes, &election); err != nil {
+		return nil, err
+	}
+	return &election, nil
+}
+
+// lockConfig records the final ConfigHash and freezes the configuration.
+func (e *Election) lockConfig() error {
+	if e.ConfigLocked {
+		return nil
+	}
+	hash, err := e.configHash()
+	if err != nil {
+		return err
+	}
+	e.ConfigHash = hash
+	e.ConfigLocked = true
+	return nil
+}
+
+// putElection stores an election, refreshing its ConfigHash unless the
+// configuration is locked.
+func putElection(ctx contractapi.TransactionContextInterface, election *Election) error {
+	if !election.ConfigLocked {
+		hash, err := election.configHash()
+		if err != nil {
+			return err
+		}
+		election.ConfigHash = hash
+	}
+
+	bytes, err := marshalState(election)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(electionKey(election.ElectionID), bytes)
+}
+
+// transitionElection moves an election to the target status, recording reason
+// in its transitions, if its current
+// status is one of the allowed source states.
+func transitionElection(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	to ElectionStatus,
+	reason string,
+	from ...ElectionStatus,
+) error {
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	allowed := false
+	for _, status := range from {
+		if election.Status == status {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return codedErrorf(ErrCodeInvalidStatus, "cannot move election %s from %s to %s", electionID, election.Status, to)
+	}
+
+	// Freeze the configuration once the election leaves DRAFT
+	if err := election.lockConfig(); err != nil {
+		return err
+	}
+	previous := election.Status
+	election.Status = to
+	if err := election.recordTransition(ctx, previous, reason); err != nil {
+		return err
+	}
+	return putElection(ctx, election)
+}
+
+// changeElectionStatus transitions an election on behalf of an election
+// official and records the change in its event log. Transactions that log
+// their own entry call transitionElection.
+func changeElectionStatus(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	to ElectionStatus,
+	reason string,
+	from ...ElectionStatus,
+) error {
+	if err := requireMSP(ctx, officialMSPs, "election official"); err != nil {
+		return err
+	}
+	if err := transitionElection(ctx, electionID, to, reason, from...); err != nil {
+		return err
+	}
+	return logEvent(ctx, electionID, EventLogStatusChanged, string(to))
+}
+
+// requireElectionOpen loads an election and returns an error unless it is
+// accepting submissions.
+func requireElectionOpen(ctx contractapi.TransactionContextInterface, electionID string) (*Election, error) {
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	switch election.Status {
+	case StatusOpen:
+		return election, nil
+	case StatusCertified, StatusArchived:
+		return nil, codedErrorf(ErrCodeElectionNotOpen, "election %s is %s and no longer accepts submissions", electionID, election.Status)
+	default:
+		return nil, codedErrorf(ErrCodeElectionNotOpen, "election %s is not open (status %s)", electionID, election.Status)
+	}
+}
+
+// requireElectionDraft loads an election whose configuration may still change.
+func requireElectionDraft(ctx contractapi.TransactionContextInterface, electionID string) (*Election, error) {
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if election.Status != StatusDraft {
+		return nil, codedErrorf(ErrCodeInvalidStatus, "election %s can only be configured while DRAFT (status %s)", electionID, election.Status)
+	}
+	if election.ConfigLocked {
+		return nil, codedErrorf(ErrCodeInvalidStatus, "election %s configuration is locked", electionID)
+	}
+	return election, nil
+}
+
+// CreateElection creates a DRAFT election from configJSON, a JSON
+// ElectionConfig. It fails if the election already exists. Only election
+// officials may call it.
+func (c *BallotContract) CreateElection(ctx contractapi.TransactionContextInterface, electionID, configJSON string) error {
+	if err := validateInputs(
+		requireKeyID("election ID", electionID),
+		maxLength("config", configJSON, maxJSONLength),
+	); err != nil {
+		return err
+	}
+	if err := requireMSP(ctx, officialMSPs, "election official"); err != nil {
+		return err
+	}
+
+	var config ElectionConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return withCode(ErrCodeInvalidArgument, err)
+	}
+	if err := validateInputs(
+		maxLength("title", config.Title, maxTextLength),
+		validateOptions(config.Options),
+		validateContests(config.Contests),
+		validateMetadataSchema(config.BallotMetadataSchema),
+		validateHashAlgorithm(config.HashAlgorithm),
+		validateTrustees(config.Trustees, config.TrusteeThreshold),
+	); err != nil {
+		return err
+	}
+	if len(config.Options) > 0 && len(config.Contests) > 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "an election defines either options or contests, not both")
+	}
+	if config.MaxClockSkewSeconds < 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "clock skew must not be negative")
+	}
+	if err := validateInputs(validateRegistrationWindow(config.RegistrationOpensAt, config.RegistrationClosesAt)); err != nil {
+		return err
+	}
+	if config.MaxVotesPerOption < 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "max votes per option must not be negative")
+	}
+	if config.MaxTotalVotes < 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "max total votes must not be negative")
+	}
+	if config.MinVoteDelaySeconds < 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "minimum vote delay must not be negative")
+	}
+	if err := validateInputs(validateQuorumThreshold(config.QuorumThreshold)); err != nil {
+		return err
+	}
+	if config.RevealTTLSeconds < 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "reveal TTL must not be negative")
+	}
+	if config.RevealNotBefore != "" {
+		if _, err := parseTimestamp(config.RevealNotBefore); err != nil {
+			return withCode(ErrCodeInvalidArgument, err)
+		}
+	}
+	switch config.DuplicatePolicy {
+	case "", DuplicateReject, DuplicateIdempotent:
+	default:
+		return codedErrorf(ErrCodeInvalidArgument, "unknown duplicate policy %q", config.DuplicatePolicy)
+	}
+
+	exists, err := ctx.GetStub().GetState(electionKey(electionID))
+	if err != nil {
+		return err
+	}
+	if exists != nil {
+		return codedErrorf(ErrCodeAlreadyExists, "election %s already exists", electionID)
+	}
+
+	election := &Election{
+		ElectionID:             electionID,
+		Title:                  config.Title,
+		Status:                 StatusDraft,
+		Options:                config.Options,
+		MaxClockSkewSeconds:    config.MaxClockSkewSeconds,
+		RegistrationOpensAt:    config.RegistrationOpensAt,
+		RegistrationClosesAt:   config.RegistrationClosesAt,
+		DuplicatePolicy:        config.DuplicatePolicy,
+		Contests:               config.Contests,
+		RevealNotBefore:        config.RevealNotBefore,
+		Trustees:               config.Trustees,
+		TrusteeThreshold:       config.TrusteeThreshold,
+		AllowUnregistered:      config.AllowUnregistered,
+		RequireVoterSignatures: config.RequireVoterSignatures,
+		MaxVotesPerOption:      config.MaxVotesPerOption,
+		BallotMetadataSchema:   config.BallotMetadataSchema,
+		Weighted:               config.Weighted,
+		HashAlgorithm:          config.HashAlgorithm,
+		MaxTotalVotes:          config.MaxTotalVotes,
+		MinVoteDelaySeconds:    config.MinVoteDelaySeconds,
+		QuorumThreshold:        config.QuorumThreshold,
+		Sealed:                 config.Sealed,
+		RevealTTLSeconds:       config.RevealTTLSeconds,
+	}
+	if err := election.recordTransition(ctx, "", ""); err != nil {
+		return err
+	}
+	if err := putElection(ctx, election); err != nil {
+		return err
+	}
+	return logEvent(ctx, electionID, EventLogElectionCreated)
+}
+
+// GetElection returns an election record.
+func (c *BallotContract) GetElection(ctx contractapi.TransactionContextInterface, electionID string) (*Election, error) {
+	return getElection(ctx, electionID)
+}
+
+// ElectionPage is a page of elections returned by ListElections.
+type ElectionPage struct {
+	Elections    []Election `json:"elections"`
+	Bookmark     string     `json:"bookmark"`
+	FetchedCount int        `json:"fetchedCount"`
+}
+
+// ListElections returns a page of all elections ordered by election ID. Pass
+// the returned bookmark to fetch the next page.
+func (c *BallotContract) ListElections(
+	ctx contractapi.TransactionContextInterface,
+	pageSize int,
+	bookmark string,
+) (*ElectionPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+
+	iterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination("election:", "election;", int32(pageSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	elections := []Election{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var election Election
+		if err := unmarshalState(record.Value, &election); err != nil {
+			return nil, err
+		}
+		elections = append(elections, election)
+	}
+
+	return &ElectionPage{
+		Elections:    elections,
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: int(metadata.GetFetchedRecordsCount()),
+	}, nil
+}
+
+// OpenRegistration starts the voter registration phase of a DRAFT election.
+// Only election officials may call it.
+func (c *BallotContract) OpenRegistration(ctx contractapi.TransactionContextInterface, electionID string) error {
+	return changeElectionStatus(ctx, electionID, StatusRegistration, "", StatusDraft)
+}
+
+// OpenElection starts accepting votes and ballots for a DRAFT election or one
+// in its registration phase. Only election officials may call it.
+func (c *BallotContract) OpenElection(ctx contractapi.TransactionContextInterface, electionID string) error {
+	return changeElectionStatus(ctx, electionID, StatusOpen, "", StatusDraft, StatusRegistration)
+}
+
+// PauseElection temporarily stops accepting submissions for an OPEN election.
+// The reason is required and recorded in the election's status history. Only
+// election officials may call it.
+func (c *BallotContract) PauseElection(ctx contractapi.TransactionContextInterface, electionID, reason string) error {
+	if err := validateInputs(requireReason("pause reason", reason)); err != nil {
+		return err
+	}
+	return changeElectionStatus(ctx, electionID, StatusPaused, reason, StatusOpen)
+}
+
+// ResumeElection reopens a PAUSED election. The reason is required and
+// recorded in the election's status history. Only election officials may call
+// it.
+func (c *BallotContract) ResumeElection(ctx contractapi.TransactionContextInterface, electionID, reason string) error {
+	if err := validateInputs(requireReason("resume reason", reason)); err != nil {
+		return err
+	}
+	return changeElectionStatus(ctx, electionID, StatusOpen, reason, StatusPaused)
+}
+
+// requireReason checks a required free-text reason.
+func requireReason(name, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("%s is required", name)
+	}
+	return maxLength(name, reason, maxTextLength)
+}
+
+// GetStatusHistory returns every status change of an election, oldest first:
+// its creation, then each transition with the status it left, the reason given
+// and the MSP of the caller that made it.
+func (c *BallotContract) GetStatusHistory(ctx contractapi.TransactionContextInterface, electionID string) ([]ElectionTransition, error) {
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if election.Transitions == nil {
+		return []ElectionTransition{}, nil
+	}
+	return election.Transitions, nil
+}
+
+// CloseElection permanently stops accepting submissions for an OPEN or PAUSED
+// election and stores a snapshot of its tally at the moment of closing, which
+// CertifyResults later certifies. Only election officials may call it.
+func (c *BallotContract) CloseElection(ctx contractapi.TransactionContextInterface, electionID string) error {
+	if err := changeElectionStatus(ctx, electionID, StatusClosed, "", StatusOpen, StatusPaused); err != nil {
+		return err
+	}
+	return snapshotTally(ctx, electionID)
+}
+
+// SetElectionOptions defines the valid option IDs for a DRAFT election.
+// optionsJSON is a JSON array of option ID strings. Only election officials
+// may call it.
+func (c *BallotContract) SetElectionOptions(ctx contractapi.TransactionContextInterface, electionID, optionsJSON string) error {
+	if err := validateInputs(maxLength("options", optionsJSON, maxJSONLength)); err != nil {
+		return err
+	}
+	if err := requireMSP(ctx, officialMSPs, "election official"); err != nil {
+		return err
+	}
+
+	var options []string
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		return withCode(ErrCodeInvalidArgument, err)
+	}
+	if err := validateInputs(validateOptions(options)); err != nil {
+		return err
+	}
+
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if len(election.Contests) > 0 && len(options) > 0 {
+		return codedErrorf(ErrCodeInvalidStatus, "election %s defines contests; options belong to each contest", electionID)
+	}
+
+	election.Options = options
+	return putElection(ctx, election)
+}
+
+// SetMaxClockSkew sets how many seconds a ballot's client timestamp may differ
+// from the transaction timestamp before it is flagged. Zero restores the default.
+func (c *BallotContract) SetMaxClockSkew(ctx contractapi.TransactionContextInterface, electionID string, seconds int) error {
+	if seconds < 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "clock skew must not be negative")
+	}
+
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	election.MaxClockSkewSeconds = seconds
+	return putElection(ctx, election)
+}
+
+// SetRegistrationWindow sets the RFC3339 times between which subjects may
+// register for a DRAFT election. Either bound may be empty. Only election
+// officials may call it.
+func (c *BallotContract) SetRegistrationWindow(
+	ctx contractapi.TransactionContextInterface,
+	electionID, opensAt, closesAt string,
+) error {
+	if err := validateInputs(validateRegistrationWindow(opensAt, closesAt)); err != nil {
+		return err
+	}
+	if err := requireMSP(ctx, officialMSPs, "election official"); err != nil {
+		return err
+	}
+
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	election.RegistrationOpensAt = opensAt
+	election.RegistrationClosesAt = closesAt
+	return putElection(ctx, election)
+}
+
+// SetRevealNotBefore sets the RFC3339 time before which a DRAFT election's
+// decryption shares may not be combined. An empty value removes the embargo.
+func (c *BallotContract) SetRevealNotBefore(ctx contractapi.TransactionContextInterface, electionID, revealNotBefore string) error {
+	if revealNotBefore != "" {
+		if _, err := parseTimestamp(revealNotBefore); err != nil {
+			return withCode(ErrCodeInvalidArgument, err)
+		}
+	}
+
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	election.RevealNotBefore = revealNotBefore
+	return putElection(ctx, election)
+}
+
+// SetMaxVotesPerOption sets how many votes an option of a DRAFT election may
+// receive before further votes for it are flagged as anomalous. Zero disables
+// the check.
+func (c *BallotContract) SetMaxVotesPerOption(ctx contractapi.TransactionContextInterface, electionID string, max int) error {
+	if max < 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "max votes per option must not be negative")
+	}
+
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	election.MaxVotesPerOption = max
+	return putElection(ctx, election)
+}
+
+// SetHashAlgorithm sets the algorithm commitment hashes in a DRAFT election
+// must be digests of: SHA-256, SHA-384 or SHA-512. Empty restores the SHA-256
+// default.
+func (c *BallotContract) SetHashAlgorithm(ctx contractapi.TransactionContextInterface, electionID, algorithm string) error {
+	if err := validateInputs(validateHashAlgorithm(algorithm)); err != nil {
+		return err
+	}
+
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	election.HashAlgorithm = algorithm
+	return putElection(ctx, election)
+}
+
+// SetWeighted sets whether votes in a DRAFT election are weighted by the
+// weights registered for their subjects.
+func (c *BallotContract) SetWeighted(ctx contractapi.TransactionContextInterface, electionID string, weighted bool) error {
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	election.Weighted = weighted
+	return putElection(ctx, election)
+}
+
+// SetSealed sets whether votes in a DRAFT election are sealed and must be
+// revealed with RevealVote before they are counted.
+func (c *BallotContract) SetSealed(ctx contractapi.TransactionContextInterface, electionID string, sealed bool) error {
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	election.Sealed = sealed
+	return putElection(ctx, election)
+}
+
+// SetRevealTTL sets how many seconds a sealed vote in a DRAFT election may
+// stay unrevealed before ExpireStaleCommitments expires it. Zero disables
+// expiry.
+func (c *BallotContract) SetRevealTTL(ctx contractapi.TransactionContextInterface, electionID string, seconds int) error {
+	if seconds < 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "reveal TTL must not be negative")
+	}
+
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	election.RevealTTLSeconds = seconds
+	return putElection(ctx, election)
+}
+
+// SetMaxTotalVotes sets the capacity of a DRAFT election: how many subjects
+// may vote and how many ballot commitments may be submitted. Zero is
+// unlimited.
+func (c *BallotContract) SetMaxTotalVotes(ctx contractapi.TransactionContextInterface, electionID string, max int) error {
+	if max < 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "max total votes must not be negative")
+	}
+
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	election.MaxTotalVotes = max
+	return putElection(ctx, election)
+}
+
+// SetMinVoteDelay sets how many seconds after registering a subject of a
+// DRAFT election must wait before voting. Zero disables the delay.
+func (c *BallotContract) SetMinVoteDelay(ctx contractapi.TransactionContextInterface, electionID string, seconds int) error {
+	if seconds < 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "minimum vote delay must not be negative")
+	}
+
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	election.MinVoteDelaySeconds = seconds
+	return putElection(ctx, election)
+}
+
+// SetQuorumThreshold sets the turnout percentage a DRAFT election needs for
+// its results to be valid. Zero disables the check.
+func (c *BallotContract) SetQuorumThreshold(ctx contractapi.TransactionContextInterface, electionID string, threshold float64) error {
+	if err := validateInputs(validateQuorumThreshold(threshold)); err != nil {
+		return err
+	}
+
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	election.QuorumThreshold = threshold
+	return putElection(ctx, election)
+}
+
+func validateQuorumThreshold(threshold float64) error {
+	if threshold < 0 || threshold > 100 {
+		return fmt.Errorf("quorum threshold must be a percentage between 0 and 100")
+	}
+	return nil
+}
+
+// SetAllowUnregistered sets whether subjects may vote in a DRAFT election
+// without registering first.
+func (c *BallotContract) SetAllowUnregistered(ctx contractapi.TransactionContextInterface, electionID string, allow bool) error {
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	election.AllowUnregistered = allow
+	return putElection(ctx, election)
+}
+
+// LockElectionConfig freezes a DRAFT election's configuration and its
+// ConfigHash. Opening registration or voting locks it automatically.
+func (c *BallotContract) LockElectionConfig(ctx contractapi.TransactionContextInterface, electionID string) (string, error) {
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := election.lockConfig(); err != nil {
+		return "", err
+	}
+	if err := putElection(ctx, election); err != nil {
+		return "", err
+	}
+	return election.ConfigHash, nil
+}
+
+// VerifyElectionConfig reports whether the election's current on-chain
+// configuration hashes to expectedHash and matches its recorded ConfigHash.
+func (c *BallotContract) VerifyElectionConfig(
+	ctx contractapi.TransactionContextInterface,
+	electionID, expectedHash string,
+) (bool, error) {
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return false, err
+	}
+
+	hash, err := election.configHash()
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(hash, expectedHash) && hash == election.ConfigHash, nil
+}
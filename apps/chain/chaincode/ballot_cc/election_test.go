@@ -0,0 +1,95 @@
+package main
+
+import "testing"
+
+func TestCastVoteAcceptsElectionOption(t *testing.T) {
+	env := newTestEnv(t)
+	env.createElection("e1", `{"title":"Board","allowUnregistered":true}`)
+	env.mustInvoke(func() error {
+		return env.contract.SetElectionOptions(env.ctx, "e1", `["yes","no"]`)
+	})
+	env.mustInvoke(func() error {
+		return env.contract.OpenElection(env.ctx, "e1")
+	})
+
+	env.castVote("e1", "subject1", testHash(1), "no")
+}
+
+func TestCastVoteRejectsUnknownOption(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+
+	err := env.invoke(func() error {
+		return env.contract.CastVote(env.ctx, "e1", "subject1", testHash(1), "maybe", "{}")
+	})
+	wantCode(t, err, ErrCodeInvalidOption)
+}
+
+func TestCastVoteRejectsElectionWithoutOptions(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","allowUnregistered":true}`)
+
+	err := env.invoke(func() error {
+		return env.contract.CastVote(env.ctx, "e1", "subject1", testHash(1), "yes", "{}")
+	})
+	wantCode(t, err, ErrCodeInvalidOption)
+}
+
+func TestSetElectionOptionsRejectsDuplicates(t *testing.T) {
+	env := newTestEnv(t)
+	env.createElection("e1", `{"title":"Board"}`)
+
+	err := env.invoke(func() error {
+		return env.contract.SetElectionOptions(env.ctx, "e1", `["yes","yes"]`)
+	})
+	wantCode(t, err, ErrCodeInvalidArgument)
+}
+
+func TestSetElectionOptionsRequiresOfficial(t *testing.T) {
+	env := newTestEnv(t)
+	env.createElection("e1", `{"title":"Board","options":["yes","no"]}`)
+
+	env.setCaller("VoterOrgMSP")
+	err := env.invoke(func() error {
+		return env.contract.SetElectionOptions(env.ctx, "e1", `["yes","no","maybe"]`)
+	})
+	wantCode(t, err, ErrCodeUnauthorized)
+}
+
+func TestCreateElectionRequiresOfficial(t *testing.T) {
+	env := newTestEnv(t)
+	env.setCaller("VoterOrgMSP")
+
+	err := env.invoke(func() error {
+		return env.contract.CreateElection(env.ctx, "e1", `{"title":"Board","options":["yes","no"]}`)
+	})
+	wantCode(t, err, ErrCodeUnauthorized)
+}
+
+func TestStatusTransitionsRequireOfficial(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"]}`)
+
+	env.setCaller("VoterOrgMSP")
+	transitions := map[string]func() error{
+		"PauseElection": func() error { return env.contract.PauseElection(env.ctx, "e1", "audit") },
+		"CloseElection": func() error { return env.contract.CloseElection(env.ctx, "e1") },
+	}
+	for name, transition := range transitions {
+		if err := env.invoke(transition); errorCode(err) != ErrCodeUnauthorized {
+			t.Errorf("%s by a non-official: got error %v, want %s", name, err, ErrCodeUnauthorized)
+		}
+	}
+
+	var election *Election
+	env.mustInvoke(func() (err error) {
+		election, err = env.contract.GetElection(env.ctx, "e1")
+		return err
+	})
+	if election.Status != StatusOpen {
+		t.Fatalf("got status %s, want OPEN", election.Status)
+	}
+	if last := election.Transitions[len(election.Transitions)-1]; last.ActorMSP != "ElectionCommissionMSP" {
+		t.Fatalf("got last transition by %q, want ElectionCommissionMSP", last.ActorMSP)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error codes prefixed to error messages, as in "ERR_ALREADY_VOTED: subject
+// already voted", so clients can tell failures apart without matching on the
+// wording. The codes are part of the contract's public interface; the
+// messages after them may change.
+const (
+	ErrCodeInvalidArgument     = "ERR_INVALID_ARGUMENT"
+	ErrCodeNotFound            = "ERR_NOT_FOUND"
+	ErrCodeAlreadyExists       = "ERR_ALREADY_EXISTS"
+	ErrCodeUnauthorized        = "ERR_UNAUTHORIZED"
+	ErrCodeInvalidStatus       = "ERR_INVALID_STATUS"
+	ErrCodeElectionNotOpen     = "ERR_ELECTION_NOT_OPEN"
+	ErrCodeRegistrationClosed  = "ERR_REGISTRATION_CLOSED"
+	ErrCodeNotRegistered       = "ERR_NOT_REGISTERED"
+	ErrCodeAlreadyVoted        = "ERR_ALREADY_VOTED"
+	ErrCodeDuplicateCommitment = "ERR_DUPLICATE_COMMITMENT"
+	ErrCodeBallotConflict      = "ERR_BALLOT_CONFLICT"
+	ErrCodeDuplicateNonce      = "ERR_DUPLICATE_NONCE"
+	ErrCodeInvalidOption       = "ERR_INVALID_OPTION"
+	ErrCodeInvalidSignature    = "ERR_INVALID_SIGNATURE"
+	ErrCodeCapacityReached     = "ERR_CAPACITY_REACHED"
+	ErrCodeVoteTooSoon         = "ERR_VOTE_TOO_SOON"
+	ErrCodeChainMismatch       = "ERR_CHAIN_MISMATCH"
+	ErrCodeRevealMismatch      = "ERR_REVEAL_MISMATCH"
+	ErrCodeVoteDelegated       = "ERR_VOTE_DELEGATED"
+	ErrCodeRevealNotPermitted  = "ERR_REVEAL_NOT_PERMITTED"
+	ErrCodeAuditChainBroken    = "ERR_AUDIT_CHAIN_BROKEN"
+	ErrCodeResultsMismatch     = "ERR_RESULTS_MISMATCH"
+)
+
+// codedError is an error carrying one of the ErrCode constants.
+type codedError struct {
+	code string
+	err  error
+}
+
+func (e *codedError) Error() string {
+	return e.code + ": " + e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// codedErrorf formats an error message prefixed with code.
+func codedErrorf(code, format string, args ...any) error {
+	return &codedError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+// withCode prefixes err with code unless it is nil or already carries one.
+func withCode(code string, err error) error {
+	if err == nil || errorCode(err) != "" {
+		return err
+	}
+	return &codedError{code: code, err: err}
+}
+
+// errorCode returns the code carried by err, or "" if it has none.
+func errorCode(err error) string {
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	return ""
+}
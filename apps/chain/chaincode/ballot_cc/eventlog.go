@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Each election keeps an append-only log of its significant actions so that
+// consumers who missed the chaincode events can replay them with GetEventLog.
+// Entries are stored under plain keys that sort in log order within each
+// election, so a replay seeks straight to the first entry it needs with a
+// range query.
+//
+// Actions other than submissions are numbered from 1 by a per-election
+// sequence counter that each of them rewrites, so a transaction logs at most
+// one numbered entry. Votes, reveals and ballot submissions would conflict
+// with each other over the counter, so their entries only read it: each
+// carries the sequence number of the numbered entry it follows and is keyed
+// by transaction timestamp, transaction ID and commitment hash after it, so
+// it sorts between that entry and the next.
+// Submissions therefore only conflict with numbered entries logged in the
+// same block.
+
+// Event log entry types that have no chaincode event of their own. The other
+// entries use the name of the event emitted with them.
+const (
+	EventLogElectionCreated    = "ElectionCreated"
+	EventLogStatusChanged      = "ElectionStatusChanged"
+	EventLogChallengeFiled     = "ChallengeFiled"
+	EventLogChallengeResolved  = "ChallengeResolved"
+	EventLogCommitmentsExpired = "CommitmentsExpired"
+)
+
+// EventLogEntry is one action recorded in an election's event log. Seq is the
+// entry's number, or for a submission entry the number of the entry it
+// follows. Refs are the keys of the records the action touched, such as
+// commitment hashes, or the new status.
+type EventLogEntry struct {
+	Seq       int      `json:"seq"`
+	Type      string   `json:"type"`
+	Timestamp string   `json:"timestamp"`
+	TxID      string   `json:"txId"`
+	Refs      []string `json:"refs,omitempty"`
+}
+
+// EventLogPage is a page of event log entries.
+type EventLogPage struct {
+	Entries      []EventLogEntry `json:"entries"`
+	Bookmark     string          `json:"bookmark"`
+	FetchedCount int             `json:"fetchedCount"`
+}
+
+func eventLogSeqKey(electionID string) string {
+	return fmt.Sprintf("eventlogseq:%s", electionID)
+}
+
+// eventLogKey returns the key of an election's numbered event log entry seq.
+// The sequence number is zero-padded so entries sort in order; submission
+// entries extend the key of the entry they follow.
+func eventLogKey(electionID string, seq int) string {
+	return fmt.Sprintf("eventlog:%s:%020d", electionID, seq)
+}
+
+// logEvent appends a numbered entry for the current transaction to an
+// election's event log.
+func logEvent(ctx contractapi.TransactionContextInterface, electionID, eventType string, refs ...string) error {
+	seq, err := readCounter(ctx, eventLogSeqKey(electionID))
+	if err != nil {
+		return err
+	}
+	seq++
+
+	if err := putEventLogEntry(ctx, eventType, seq, eventLogKey(electionID, seq), refs); err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(eventLogSeqKey(electionID), []byte(fmt.Sprint(seq)))
+}
+
+// logSubmission appends an entry for a submission of commitmentHash in the
+// current transaction to an election's event log, after its latest numbered
+// entry.
+func logSubmission(ctx contractapi.TransactionContextInterface, electionID, eventType, commitmentHash string) error {
+	seq, err := readCounter(ctx, eventLogSeqKey(electionID))
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s:%020d:%s:%s", eventLogKey(electionID, seq), now.UnixNano(), ctx.GetStub().GetTxID(), commitmentHash)
+	return putEventLogEntry(ctx, eventType, seq, key, []string{commitmentHash})
+}
+
+func putEventLogEntry(ctx contractapi.TransactionContextInterface, eventType string, seq int, key string, refs []string) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	bytes, err := marshalState(EventLogEntry{
+		Seq:       seq,
+		Type:      eventType,
+		Timestamp: now.Format(time.RFC3339Nano),
+		TxID:      ctx.GetStub().GetTxID(),
+		Refs:      refs,
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, bytes)
+}
+
+// GetEventLog returns a page of an election's event log entries after the
+// numbered entry fromSeq, oldest first: numbered entries with a higher Seq
+// and submission entries following fromSeq or later. Pass 0 to start from
+// the beginning. The range query starts at the first such entry, so earlier
+// entries are never read; continue with the returned bookmark and the same
+// fromSeq.
+func (c *BallotContract) GetEventLog(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	fromSeq, pageSize int,
+	bookmark string,
+) (*EventLogPage, error) {
+	if fromSeq < 0 {
+		return nil, codedErrorf(ErrCodeInvalidArgument, "sequence number must not be negative")
+	}
+	if pageSize <= 0 {
+		return nil, codedErrorf(ErrCodeInvalidArgument, "page size must be positive")
+	}
+	if _, err := getElection(ctx, electionID); err != nil {
+		return nil, err
+	}
+
+	// Submission entries following fromSeq extend its key with ':', which
+	// sorts after the entry itself; ';' sorts after every entry's key
+	startKey := eventLogKey(electionID, fromSeq) + ":"
+	endKey := fmt.Sprintf("eventlog:%s;", electionID)
+	iterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination(startKey, endKey, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	entries := []EventLogEntry{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var entry EventLogEntry
+		if err := unmarshalState(record.Value, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return &EventLogPage{
+		Entries:      entries,
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: int(metadata.GetFetchedRecordsCount()),
+	}, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestGetEventLogSeeksPastFromSeq(t *testing.T) {
+	env := newTestEnv(t)
+	stub := &paginatingStub{MockStub: env.stub}
+	env.ctx.SetStub(stub)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	env.castVote("e1", "subject2", testHash(2), "no")
+	env.mustInvoke(func() error {
+		return env.contract.PauseElection(env.ctx, "e1", "audit")
+	})
+
+	stub.reads = 0
+	var page *EventLogPage
+	env.mustInvoke(func() (err error) {
+		page, err = env.contract.GetEventLog(env.ctx, "e1", 2, 2, "")
+		return err
+	})
+	if stub.reads != 2 || len(page.Entries) != 2 || page.FetchedCount != 2 {
+		t.Fatalf("got %d entries from %d reads, want the page to hold both votes read", len(page.Entries), stub.reads)
+	}
+	for i, entry := range page.Entries {
+		if entry.Seq != 2 || entry.Type != EventVoteCast {
+			t.Errorf("got entry %d %+v, want a vote following entry 2", i, entry)
+		}
+	}
+
+	env.mustInvoke(func() (err error) {
+		page, err = env.contract.GetEventLog(env.ctx, "e1", 2, 2, page.Bookmark)
+		return err
+	})
+	if len(page.Entries) != 1 || page.Entries[0].Seq != 3 || page.Bookmark != "" {
+		t.Fatalf("got last page %+v, want only entry 3", page)
+	}
+}
+
+func TestGetEventLogFromStart(t *testing.T) {
+	env := newTestEnv(t)
+	env.ctx.SetStub(&paginatingStub{MockStub: env.stub})
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.openElection("e2", `{"title":"Budget","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+
+	var page *EventLogPage
+	env.mustInvoke(func() (err error) {
+		page, err = env.contract.GetEventLog(env.ctx, "e1", 0, 10, "")
+		return err
+	})
+	want := []string{EventLogElectionCreated, EventLogStatusChanged, EventVoteCast}
+	if len(page.Entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(page.Entries), len(want))
+	}
+	for i, entry := range page.Entries {
+		if entry.Type != want[i] {
+			t.Errorf("got entry %d of type %s, want %s", i, entry.Type, want[i])
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Chaincode event names. These are part of the contract's public interface and
+// are subscribed to by the indexer and receipt notifier, so they must not change.
+// Fabric delivers at most one event per transaction.
+const (
+	EventVoteCast             = "VoteCast"
+	EventBallotCommitted      = "BallotCommitted"
+	EventBallotBatchCommitted = "BallotBatchCommitted"
+	EventBallotSpoiled        = "BallotSpoiled"
+	EventResultsCertified     = "ResultsCertified"
+	EventResultsAmended       = "ResultsAmended"
+	EventElectionArchived     = "ElectionArchived"
+	EventVoteInvalidated      = "VoteInvalidated"
+	EventProvisionalResolved  = "ProvisionalVoteResolved"
+	EventRawStateRead         = "RawStateRead"
+	EventVoteRevealed         = "VoteRevealed"
+)
+
+// SubmissionEvent is the payload of VoteCast, VoteRevealed and
+// BallotCommitted events.
+type SubmissionEvent struct {
+	ElectionID     string `json:"electionId"`
+	CommitmentHash string `json:"commitmentHash"`
+	TxID           string `json:"txId"`
+	Timestamp      string `json:"timestamp"`
+}
+
+// BallotSpoiledEvent is the payload of BallotSpoiled events.
+type BallotSpoiledEvent struct {
+	ElectionID     string `json:"electionId"`
+	CommitmentHash string `json:"commitmentHash"`
+	Reason         string `json:"reason"`
+	TxID           string `json:"txId"`
+	Timestamp      string `json:"timestamp"`
+}
+
+// VoteInvalidatedEvent is the payload of VoteInvalidated events.
+type VoteInvalidatedEvent struct {
+	ElectionID       string   `json:"electionId"`
+	SubjectHash      string   `json:"subjectHash"`
+	CommitmentHashes []string `json:"commitmentHashes"`
+	Reason           string   `json:"reason"`
+	OfficialID       string   `json:"officialId"`
+	TxID             string   `json:"txId"`
+	Timestamp        string   `json:"timestamp"`
+}
+
+// ProvisionalResolvedEvent is the payload of ProvisionalVoteResolved events.
+type ProvisionalResolvedEvent struct {
+	ElectionID     string `json:"electionId"`
+	CommitmentHash string `json:"commitmentHash"`
+	Status         string `json:"status"`
+	Reason         string `json:"reason,omitempty"`
+	OfficialID     string `json:"officialId"`
+	TxID           string `json:"txId"`
+	Timestamp      string `json:"timestamp"`
+}
+
+// RawStateReadEvent is the payload of RawStateRead events.
+type RawStateReadEvent struct {
+	Key       string `json:"key"`
+	MSPID     string `json:"mspId"`
+	ClientID  string `json:"clientId"`
+	TxID      string `json:"txId"`
+	Timestamp string `json:"timestamp"`
+}
+
+// ResultsCertifiedEvent is the payload of ResultsCertified events.
+type ResultsCertifiedEvent struct {
+	ElectionID  string `json:"electionId"`
+	ResultsHash string `json:"resultsHash"`
+	TotalVotes  int    `json:"totalVotes"`
+	CertifiedAt string `json:"certifiedAt"`
+	CertifierID string `json:"certifierId"`
+	QuorumMet   bool   `json:"quorumMet"`
+}
+
+// ResultsAmendedEvent is the payload of ResultsAmended events.
+type ResultsAmendedEvent struct {
+	ElectionID          string `json:"electionId"`
+	ResultsHash         string `json:"resultsHash"`
+	PreviousResultsHash string `json:"previousResultsHash"`
+	TotalVotes          int    `json:"totalVotes"`
+	Reason              string `json:"reason"`
+	CertifierID         string `json:"certifierId"`
+}
+
+// txTime returns the transaction timestamp assigned by the submitting client
+// and validated by endorsing peers.
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(ts.GetSeconds(), int64(ts.GetNanos())).UTC(), nil
+}
+
+// clientTimestampLayouts are the accepted formats for caller-supplied
+// timestamps. Timestamps without a zone are interpreted as UTC.
+var clientTimestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+}
+
+// parseTimestamp parses a caller-supplied timestamp.
+func parseTimestamp(value string) (time.Time, error) {
+	for _, layout := range clientTimestampLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q: expected RFC3339", value)
+}
+
+// emitEvent serializes payload and sets it as the transaction's chaincode event.
+func emitEvent(ctx contractapi.TransactionContextInterface, name string, payload any) error {
+	bytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().SetEvent(name, bytes)
+}
+
+// emitSubmissionEvent emits a SubmissionEvent for the current transaction and
+// records the submission in the election's event log.
+func emitSubmissionEvent(ctx contractapi.TransactionContextInterface, name, electionID, commitmentHash string) error {
+	if err := logSubmission(ctx, electionID, name, commitmentHash); err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	return emitEvent(ctx, name, SubmissionEvent{
+		ElectionID:     electionID,
+		CommitmentHash: commitmentHash,
+		TxID:           ctx.GetStub().GetTxID(),
+		Timestamp:      now.Format(time.RFC3339Nano),
+	})
+}
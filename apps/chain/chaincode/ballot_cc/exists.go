@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// stateExists reports whether a value is stored under key.
+func stateExists(ctx contractapi.TransactionContextInterface, key string) (bool, error) {
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, err
+	}
+	return bytes != nil, nil
+}
+
+// BallotExists reports whether a ballot commitment is recorded in an election,
+// as a cheap pre-flight check before submitting one.
+func (c *BallotContract) BallotExists(ctx contractapi.TransactionContextInterface, electionID, commitmentHash string) (bool, error) {
+	key, err := ballotKey(ctx, electionID, commitmentHash)
+	if err != nil {
+		return false, err
+	}
+	return stateExists(ctx, key)
+}
+
+// VoteExists reports whether a vote is recorded under a commitment in an
+// election.
+func (c *BallotContract) VoteExists(ctx contractapi.TransactionContextInterface, electionID, commitmentHash string) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{electionID, commitmentHash})
+	if err != nil {
+		return false, err
+	}
+	return stateExists(ctx, key)
+}
+
+// SubjectExists reports whether a subject is registered for an election.
+func (c *BallotContract) SubjectExists(ctx contractapi.TransactionContextInterface, electionID, subjectHash string) (bool, error) {
+	return stateExists(ctx, subjectKey(electionID, subjectHash))
+}
@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestExistenceChecks(t *testing.T) {
+	env := newTestEnv(t)
+	env.openWithSubjects("e1", `{"title":"Board","options":["yes","no"]}`, "subject1")
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	env.mustInvoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot1", testHash(2), "", "")
+	})
+
+	counter := &readCountingStub{MockStub: env.stub}
+	env.ctx.SetStub(counter)
+	c := env.contract
+	checks := []struct {
+		name  string
+		check func() (bool, error)
+		want  bool
+	}{
+		{"recorded ballot", func() (bool, error) { return c.BallotExists(env.ctx, "e1", testHash(2)) }, true},
+		{"unknown ballot", func() (bool, error) { return c.BallotExists(env.ctx, "e1", testHash(3)) }, false},
+		{"ballot in other election", func() (bool, error) { return c.BallotExists(env.ctx, "e2", testHash(2)) }, false},
+		{"vote hash as ballot", func() (bool, error) { return c.BallotExists(env.ctx, "e1", testHash(1)) }, false},
+		{"recorded vote", func() (bool, error) { return c.VoteExists(env.ctx, "e1", testHash(1)) }, true},
+		{"unknown vote", func() (bool, error) { return c.VoteExists(env.ctx, "e1", testHash(3)) }, false},
+		{"ballot hash as vote", func() (bool, error) { return c.VoteExists(env.ctx, "e1", testHash(2)) }, false},
+		{"registered subject", func() (bool, error) { return c.SubjectExists(env.ctx, "e1", "subject1") }, true},
+		{"unknown subject", func() (bool, error) { return c.SubjectExists(env.ctx, "e1", "subject2") }, false},
+	}
+	for _, tc := range checks {
+		counter.reads, counter.scans = 0, 0
+		var got bool
+		env.mustInvoke(func() (err error) {
+			got, err = tc.check()
+			return err
+		})
+		if got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+		if counter.reads != 1 || counter.scans != 0 {
+			t.Errorf("%s: made %d reads and %d scans, want a single read", tc.name, counter.reads, counter.scans)
+		}
+	}
+}
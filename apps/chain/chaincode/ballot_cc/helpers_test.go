@@ -0,0 +1,294 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// testEnv runs contract calls against a MockStub, each in its own mock
+// transaction, as a client of one MSP at a time.
+type testEnv struct {
+	t        *testing.T
+	stub     *shimtest.MockStub
+	ctx      *contractapi.TransactionContext
+	contract *BallotContract
+	txCount  int
+}
+
+// newTestEnv returns a testEnv whose caller is an election official.
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	stub := shimtest.NewMockStub("ballot_cc", nil)
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	env := &testEnv{t: t, stub: stub, ctx: ctx, contract: &BallotContract{}}
+	env.setCaller("ElectionCommissionMSP")
+	return env
+}
+
+// setCaller makes a new client identity of mspID the caller of later
+// transactions.
+func (env *testEnv) setCaller(mspID string) {
+	env.t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "user@" + mspID},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	creator, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   mspID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	})
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	env.stub.Creator = creator
+
+	identity, err := cid.New(env.stub)
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	env.ctx.SetClientIdentity(identity)
+}
+
+// recordHistory makes later transactions record the history of every key they
+// write, for GetHistoryForKey.
+func (env *testEnv) recordHistory() {
+	env.ctx.SetStub(&historyStub{MockStub: env.stub, history: map[string][]*queryresult.KeyModification{}})
+}
+
+// invoke runs fn in a new mock transaction and returns its error. The
+// transaction's events are discarded, since MockStub blocks once 100 are
+// pending.
+func (env *testEnv) invoke(fn func() error) error {
+	env.txCount++
+	txID := fmt.Sprintf("tx%d", env.txCount)
+	env.stub.MockTransactionStart(txID)
+	defer env.stub.MockTransactionEnd(txID)
+	defer env.drainEvents()
+	return fn()
+}
+
+func (env *testEnv) drainEvents() {
+	for {
+		select {
+		case <-env.stub.ChaincodeEventsChannel:
+		default:
+			return
+		}
+	}
+}
+
+// mustInvoke runs fn in a new mock transaction and fails the test if it
+// returns an error.
+func (env *testEnv) mustInvoke(fn func() error) {
+	env.t.Helper()
+	if err := env.invoke(fn); err != nil {
+		env.t.Fatal(err)
+	}
+}
+
+// createElection creates a DRAFT election from configJSON.
+func (env *testEnv) createElection(electionID, configJSON string) {
+	env.t.Helper()
+	env.mustInvoke(func() error {
+		return env.contract.CreateElection(env.ctx, electionID, configJSON)
+	})
+}
+
+// openElection creates an OPEN election from configJSON.
+func (env *testEnv) openElection(electionID, configJSON string) {
+	env.t.Helper()
+	env.createElection(electionID, configJSON)
+	env.mustInvoke(func() error {
+		return env.contract.OpenElection(env.ctx, electionID)
+	})
+}
+
+// castVote casts a vote and fails the test if it is rejected.
+func (env *testEnv) castVote(electionID, subjectHash, commitmentHash, optionID string) {
+	env.t.Helper()
+	env.mustInvoke(func() error {
+		return env.contract.CastVote(env.ctx, electionID, subjectHash, commitmentHash, optionID, "{}")
+	})
+}
+
+// testHash returns a distinct SHA-256-length hex hash for n.
+func testHash(n int) string {
+	return fmt.Sprintf("%064x", n)
+}
+
+// wantCode fails the test unless err carries code.
+func wantCode(t *testing.T, err error, code string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("got no error, want %s", code)
+	}
+	if got := errorCode(err); got != code {
+		t.Fatalf("got error %q, want code %s", err, code)
+	}
+}
+
+// historyStub adds the key history MockStub does not implement: it records
+// each PutState and DelState with its transaction, and GetHistoryForKey
+// returns them newest first, as the peer's history database does.
+type historyStub struct {
+	*shimtest.MockStub
+	history map[string][]*queryresult.KeyModification
+}
+
+func (s *historyStub) PutState(key string, value []byte) error {
+	if err := s.MockStub.PutState(key, value); err != nil {
+		return err
+	}
+	s.record(key, value, false)
+	return nil
+}
+
+func (s *historyStub) DelState(key string) error {
+	if err := s.MockStub.DelState(key); err != nil {
+		return err
+	}
+	s.record(key, nil, true)
+	return nil
+}
+
+func (s *historyStub) record(key string, value []byte, deleted bool) {
+	modification := &queryresult.KeyModification{
+		TxId:      s.GetTxID(),
+		Value:     value,
+		Timestamp: s.TxTimestamp,
+		IsDelete:  deleted,
+	}
+	s.history[key] = append([]*queryresult.KeyModification{modification}, s.history[key]...)
+}
+
+func (s *historyStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &historyIterator{modifications: s.history[key]}, nil
+}
+
+type historyIterator struct {
+	modifications []*queryresult.KeyModification
+}
+
+func (it *historyIterator) HasNext() bool {
+	return len(it.modifications) > 0
+}
+
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	modification := it.modifications[0]
+	it.modifications = it.modifications[1:]
+	return modification, nil
+}
+
+func (it *historyIterator) Close() error {
+	return nil
+}
+
+// paginatingStub adds the paginated queries MockStub does not implement. As
+// on a peer, a bookmark is the key the next page starts at.
+type paginatingStub struct {
+	*shimtest.MockStub
+	reads int
+}
+
+func (s *paginatingStub) GetStateByRangeWithPagination(
+	startKey, endKey string,
+	pageSize int32,
+	bookmark string,
+) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if bookmark != "" {
+		startKey = bookmark
+	}
+	iterator, err := s.MockStub.GetStateByRange(startKey, endKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.page(iterator, pageSize, "")
+}
+
+func (s *paginatingStub) GetStateByPartialCompositeKeyWithPagination(
+	objectType string,
+	attributes []string,
+	pageSize int32,
+	bookmark string,
+) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	iterator, err := s.MockStub.GetStateByPartialCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.page(iterator, pageSize, bookmark)
+}
+
+// page reads up to pageSize records from iterator, starting at bookmark.
+func (s *paginatingStub) page(
+	iterator shim.StateQueryIteratorInterface,
+	pageSize int32,
+	bookmark string,
+) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	defer iterator.Close()
+
+	page := &pagedIterator{}
+	metadata := &pb.QueryResponseMetadata{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if record.Key < bookmark {
+			continue
+		}
+		if len(page.records) == int(pageSize) {
+			metadata.Bookmark = record.Key
+			break
+		}
+		s.reads++
+		page.records = append(page.records, record)
+	}
+	metadata.FetchedRecordsCount = int32(len(page.records))
+	return page, metadata, nil
+}
+
+type pagedIterator struct {
+	records []*queryresult.KV
+}
+
+func (it *pagedIterator) HasNext() bool {
+	return len(it.records) > 0
+}
+
+func (it *pagedIterator) Next() (*queryresult.KV, error) {
+	record := it.records[0]
+	it.records = it.records[1:]
+	return record, nil
+}
+
+func (it *pagedIterator) Close() error {
+	return nil
+}
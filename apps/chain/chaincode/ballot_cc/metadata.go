@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Ballot metadata value types a MetadataField may require.
+const (
+	MetadataString  = "string"
+	MetadataNumber  = "number"
+	MetadataBoolean = "boolean"
+	MetadataObject  = "object"
+	MetadataArray   = "array"
+)
+
+// MetadataField constrains one key of a ballot's metadata. An empty Type
+// accepts any value.
+type MetadataField struct {
+	Type     string `json:"type,omitempty"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// validateMetadataSchema checks a ballot metadata schema's keys and types.
+func validateMetadataSchema(schema map[string]MetadataField) error {
+	for key, field := range schema {
+		if err := requireID("metadata key", key); err != nil {
+			return err
+		}
+		switch field.Type {
+		case "", MetadataString, MetadataNumber, MetadataBoolean, MetadataObject, MetadataArray:
+		default:
+			return fmt.Errorf("unknown metadata type %q for key %q", field.Type, key)
+		}
+	}
+	return nil
+}
+
+// metadataType returns the schema type name of a decoded JSON value.
+func metadataType(value any) string {
+	switch value.(type) {
+	case string:
+		return MetadataString
+	case float64, json.Number:
+		return MetadataNumber
+	case bool:
+		return MetadataBoolean
+	case map[string]any:
+		return MetadataObject
+	case []any:
+		return MetadataArray
+	default:
+		return "null"
+	}
+}
+
+// checkBallotMetadata validates a ballot's metadata against the election's
+// schema, if it has one. Keys not in the schema are allowed.
+func (e *Election) checkBallotMetadata(metadata map[string]any) error {
+	keys := make([]string, 0, len(e.BallotMetadataSchema))
+	for key := range e.BallotMetadataSchema {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field := e.BallotMetadataSchema[key]
+		value, ok := metadata[key]
+		if !ok {
+			if field.Required {
+				return codedErrorf(ErrCodeInvalidArgument, "ballot metadata is missing required key %q", key)
+			}
+			continue
+		}
+		if field.Type != "" && metadataType(value) != field.Type {
+			return codedErrorf(ErrCodeInvalidArgument, "ballot metadata key %q must be a %s, got %s", key, field.Type, metadataType(value))
+		}
+	}
+	return nil
+}
+
+// SetBallotMetadataSchema sets the metadata schema ballots submitted to a
+// DRAFT election must satisfy. schemaJSON is a JSON object mapping metadata
+// keys to {type, required}, e.g. {"stationId":{"type":"string","required":true}}.
+// An empty object removes the schema.
+func (c *BallotContract) SetBallotMetadataSchema(ctx contractapi.TransactionContextInterface, electionID, schemaJSON string) error {
+	if err := validateInputs(maxLength("metadata schema", schemaJSON, maxJSONLength)); err != nil {
+		return err
+	}
+
+	var schema map[string]MetadataField
+	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
+		return withCode(ErrCodeInvalidArgument, err)
+	}
+	if err := validateInputs(validateMetadataSchema(schema)); err != nil {
+		return err
+	}
+
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	if len(schema) == 0 {
+		schema = nil
+	}
+	election.BallotMetadataSchema = schema
+	return putElection(ctx, election)
+}
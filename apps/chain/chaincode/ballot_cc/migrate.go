@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// The first releases stored votes under "vote:<electionID>:<commitmentHash>"
+// and ballots under "ballot:<electionID>:<commitmentHash>". Nothing reads
+// those keys any more; MigrateKeys moves them to the composite keys.
+
+func legacyVoteKeyPrefix(electionID string) string {
+	return fmt.Sprintf("vote:%s:", electionID)
+}
+
+func legacyBallotKeyPrefix(electionID string) string {
+	return fmt.Sprintf("ballot:%s:", electionID)
+}
+
+// keyMigrationKey holds the ID of the transaction that completed an
+// election's key migration.
+func keyMigrationKey(electionID string) string {
+	return fmt.Sprintf("migrated:%s", electionID)
+}
+
+// KeyMigration reports the progress of a MigrateKeys call.
+type KeyMigration struct {
+	ElectionID string `json:"electionId"`
+	Votes      int    `json:"votes"`
+	Ballots    int    `json:"ballots"`
+	Skipped    int    `json:"skipped"`
+	Bookmark   string `json:"bookmark"`
+	Complete   bool   `json:"complete"`
+}
+
+// legacyRange is one legacy key range a migration scans.
+type legacyRange struct {
+	start, end string
+	migrate    func(value []byte) (bool, error)
+}
+
+// MigrateKeys moves up to pageSize of an election's votes and ballots stored
+// under the legacy plain keys to the composite keys used now, writing the
+// indexes, has-voted markers and counters they were recorded without, and
+// deletes the legacy keys, whose history stays on the ledger. Entries whose
+// composite key is already taken are left in place and counted as Skipped.
+//
+// Ballots are migrated before votes. Pass the returned bookmark to continue
+// where a call stopped; an empty bookmark starts from the beginning. The call
+// that reaches the end of the legacy keys marks the migration complete, after
+// which further calls do nothing. Only callers from an administrator
+// organization may call it.
+func (c *BallotContract) MigrateKeys(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	pageSize int,
+	bookmark string,
+) (*KeyMigration, error) {
+	if err := validateInputs(requireKeyID("election ID", electionID)); err != nil {
+		return nil, err
+	}
+	if pageSize <= 0 {
+		return nil, codedErrorf(ErrCodeInvalidArgument, "page size must be positive")
+	}
+	if err := requireMSP(ctx, adminMSPs, "administrator"); err != nil {
+		return nil, err
+	}
+
+	result := &KeyMigration{ElectionID: electionID}
+	done, err := ctx.GetStub().GetState(keyMigrationKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	if done != nil {
+		result.Complete = true
+		return result, nil
+	}
+
+	// Writes made earlier in this transaction are not visible to GetState, so
+	// subjects marked as voted and counter increments are tracked in memory
+	voted := map[string]bool{}
+	voters, ballots := 0, 0
+
+	migrateVote := func(value []byte) (bool, error) {
+		var vote VoteCommitment
+		if err := unmarshalState(value, &vote); err != nil {
+			return false, err
+		}
+		if vote.ElectionID != electionID {
+			return false, nil
+		}
+		voteKey, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{electionID, vote.CommitmentHash})
+		if err != nil {
+			return false, err
+		}
+		exists, err := ctx.GetStub().GetState(voteKey)
+		if err != nil {
+			return false, err
+		}
+		if exists != nil {
+			return false, nil
+		}
+
+		bytes, err := marshalState(vote)
+		if err != nil {
+			return false, err
+		}
+		if err := ctx.GetStub().PutState(voteKey, bytes); err != nil {
+			return false, err
+		}
+		indexes := [][]string{
+			{voteCommitmentIndex, vote.CommitmentHash, electionID},
+			{voteSubjectIndex, electionID, vote.voter(), vote.CommitmentHash},
+			{voteOptionIndex, electionID, vote.tallyOption(), vote.CommitmentHash},
+		}
+		for _, index := range indexes {
+			indexKey, err := ctx.GetStub().CreateCompositeKey(index[0], index[1:])
+			if err != nil {
+				return false, err
+			}
+			if err := ctx.GetStub().PutState(indexKey, []byte{0x00}); err != nil {
+				return false, err
+			}
+		}
+
+		if vote.SubjectHash != "" && !voted[vote.SubjectHash] {
+			marker, err := ctx.GetStub().GetState(votedKey(electionID, vote.SubjectHash))
+			if err != nil {
+				return false, err
+			}
+			if marker == nil {
+				if err := ctx.GetStub().PutState(votedKey(electionID, vote.SubjectHash), []byte(ctx.GetStub().GetTxID())); err != nil {
+					return false, err
+				}
+				voters++
+			}
+			voted[vote.SubjectHash] = true
+		}
+		result.Votes++
+		return true, nil
+	}
+
+	migrateBallot := func(value []byte) (bool, error) {
+		var ballot BallotCommitment
+		if err := unmarshalState(value, &ballot); err != nil {
+			return false, err
+		}
+		if ballot.ElectionID != electionID {
+			return false, nil
+		}
+		compositeKey, err := ballotKey(ctx, electionID, ballot.CommitmentHash)
+		if err != nil {
+			return false, err
+		}
+		exists, err := ctx.GetStub().GetState(compositeKey)
+		if err != nil {
+			return false, err
+		}
+		if exists != nil {
+			return false, nil
+		}
+
+		if err := putBallot(ctx, compositeKey, &ballot); err != nil {
+			return false, err
+		}
+		if err := indexBallot(ctx, electionID, ballot.CommitmentHash); err != nil {
+			return false, err
+		}
+		if ballot.BallotID != "" {
+			idKey := ballotIDKey(electionID, ballot.BallotID)
+			existing, err := ctx.GetStub().GetState(idKey)
+			if err != nil {
+				return false, err
+			}
+			if existing == nil {
+				if err := ctx.GetStub().PutState(idKey, []byte(ballot.CommitmentHash)); err != nil {
+					return false, err
+				}
+			}
+		}
+		if err := indexBallotSubject(ctx, &ballot); err != nil {
+			return false, err
+		}
+		ballots++
+		result.Ballots++
+		return true, nil
+	}
+
+	// Both ranges end just before ';', the character after ':', so they
+	// exclude keys such as "voted:" and "ballotid:". Ballot keys sort first,
+	// which lets a single bookmark cover both.
+	ranges := []legacyRange{
+		{legacyBallotKeyPrefix(electionID), fmt.Sprintf("ballot:%s;", electionID), migrateBallot},
+		{legacyVoteKeyPrefix(electionID), fmt.Sprintf("vote:%s;", electionID), migrateVote},
+	}
+
+	// Read the whole page first so every range query is closed before the
+	// keys it returned are rewritten
+	type legacyEntry struct {
+		key     string
+		value   []byte
+		migrate func([]byte) (bool, error)
+	}
+	page := []legacyEntry{}
+	more := false
+	for _, r := range ranges {
+		if more || bookmark >= r.end {
+			continue
+		}
+		start := r.start
+		if bookmark >= start {
+			start = bookmark + "\x00"
+		}
+
+		iterator, err := ctx.GetStub().GetStateByRange(start, r.end)
+		if err != nil {
+			return nil, err
+		}
+		for iterator.HasNext() {
+			if len(page) == pageSize {
+				more = true
+				break
+			}
+			record, err := iterator.Next()
+			if err != nil {
+				iterator.Close()
+				return nil, err
+			}
+			page = append(page, legacyEntry{record.Key, record.Value, r.migrate})
+		}
+		iterator.Close()
+	}
+
+	for _, entry := range page {
+		migrated, err := entry.migrate(entry.value)
+		if err != nil {
+			return nil, err
+		}
+		if migrated {
+			if err := ctx.GetStub().DelState(entry.key); err != nil {
+				return nil, err
+			}
+		} else {
+			result.Skipped++
+		}
+		result.Bookmark = entry.key
+	}
+
+	if voters > 0 {
+		if err := addToVoteCount(ctx, electionID, voters); err != nil {
+			return nil, err
+		}
+	}
+	if ballots > 0 {
+		if err := addToCounter(ctx, ballotCountKey(electionID), ballots); err != nil {
+			return nil, err
+		}
+	}
+
+	if !more {
+		result.Bookmark = ""
+		result.Complete = true
+		if err := ctx.GetStub().PutState(keyMigrationKey(electionID), []byte(ctx.GetStub().GetTxID())); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
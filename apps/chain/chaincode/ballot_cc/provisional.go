@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// provisionalVoteIndex lists the provisional votes of an election awaiting
+// review.
+const provisionalVoteIndex = "provisional~vote"
+
+// Review states of a provisional vote.
+const (
+	ProvisionalPending   = "PENDING"
+	ProvisionalConfirmed = "CONFIRMED"
+	ProvisionalRejected  = "REJECTED"
+)
+
+// CastProvisionalVote records a vote whose voter's eligibility is still to be
+// confirmed. It behaves like CastVote except that the subject need not be
+// registered, and the vote is not counted, listed by GetVotesByOption or
+// checked against MaxVotesPerOption until an official confirms it with
+// ConfirmProvisionalVote. The subject is marked as having voted either way.
+// Emits a "VoteCast" event.
+func (c *BallotContract) CastProvisionalVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID, subjectHash, commitmentHash, optionID, metaJSON string,
+) error {
+	if err := validateInputs(maxLength("metadata", metaJSON, maxJSONLength)); err != nil {
+		return err
+	}
+	var meta map[string]any
+	if err := json.Unmarshal([]byte(metaJSON), &meta); err != nil {
+		return withCode(ErrCodeInvalidArgument, err)
+	}
+
+	return recordVote(ctx, &VoteCommitment{
+		ElectionID:        electionID,
+		SubjectHash:       subjectHash,
+		CommitmentHash:    commitmentHash,
+		OptionID:          optionID,
+		Meta:              meta,
+		Provisional:       true,
+		ProvisionalStatus: ProvisionalPending,
+	})
+}
+
+// ConfirmProvisionalVote promotes a pending provisional vote into the count
+// once the voter's eligibility has been confirmed. Only election officials may
+// call it. Emits a "ProvisionalVoteResolved" event.
+func (c *BallotContract) ConfirmProvisionalVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID, commitmentHash, officialID string,
+) error {
+	return resolveProvisionalVote(ctx, electionID, commitmentHash, ProvisionalConfirmed, "", officialID)
+}
+
+// RejectProvisionalVote marks a pending provisional vote rejected with a
+// reason, so it is never counted. The vote is kept for audit and the subject
+// remains marked as having voted. Only election officials may call it. Emits a
+// "ProvisionalVoteResolved" event.
+func (c *BallotContract) RejectProvisionalVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID, commitmentHash, reason, officialID string,
+) error {
+	if err := validateInputs(maxLength("rejection reason", reason, maxTextLength)); err != nil {
+		return err
+	}
+	if reason == "" {
+		return codedErrorf(ErrCodeInvalidArgument, "rejection reason is required")
+	}
+	return resolveProvisionalVote(ctx, electionID, commitmentHash, ProvisionalRejected, reason, officialID)
+}
+
+// resolveProvisionalVote records the outcome of reviewing a pending
+// provisional vote and removes it from the review queue. Votes may be resolved
+// while the election is OPEN, PAUSED or CLOSED; confirming one after closing
+// adds it to the tally snapshot so certification covers it.
+func resolveProvisionalVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID, commitmentHash, status, reason, officialID string,
+) error {
+	if err := validateInputs(
+		requireKeyID("election ID", electionID),
+		requireHash("commitment hash", commitmentHash),
+		requireID("official ID", officialID),
+	); err != nil {
+		return err
+	}
+	if err := requireMSP(ctx, officialMSPs, "election official"); err != nil {
+		return err
+	}
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	switch election.Status {
+	case StatusOpen, StatusPaused, StatusClosed:
+	default:
+		return codedErrorf(ErrCodeInvalidStatus, "provisional votes cannot be resolved while election %s is %s", electionID, election.Status)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{electionID, commitmentHash})
+	if err != nil {
+		return err
+	}
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return err
+	}
+	if bytes == nil {
+		return codedErrorf(ErrCodeNotFound, "vote %s not found in election %s", commitmentHash, electionID)
+	}
+
+	var vote VoteCommitment
+	if err := unmarshalState(bytes, &vote); err != nil {
+		return err
+	}
+	if !vote.Provisional {
+		return codedErrorf(ErrCodeInvalidStatus, "vote %s is not provisional", commitmentHash)
+	}
+	if vote.ProvisionalStatus != ProvisionalPending {
+		return codedErrorf(ErrCodeInvalidStatus, "provisional vote %s is already %s", commitmentHash, vote.ProvisionalStatus)
+	}
+	if vote.Invalidated {
+		return codedErrorf(ErrCodeInvalidStatus, "provisional vote %s has been invalidated", commitmentHash)
+	}
+
+	vote.ProvisionalStatus = status
+	vote.ProvisionalReason = reason
+	vote.ProvisionalReviewedBy = officialID
+	// A confirmed vote now counts towards its option
+	if election.MaxVotesPerOption > 0 && vote.optionIndexed() {
+		if err := flagOptionBurst(ctx, election, &vote, 1); err != nil {
+			return err
+		}
+	}
+	bytes, err = marshalState(vote)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, bytes); err != nil {
+		return err
+	}
+
+	indexKey, err := ctx.GetStub().CreateCompositeKey(provisionalVoteIndex, []string{electionID, commitmentHash})
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().DelState(indexKey); err != nil {
+		return err
+	}
+	// A confirmed vote joins its option's index; a rejected one is removed
+	// from it in case it was indexed when cast
+	if vote.optionIndexed() {
+		if err := indexVoteOption(ctx, &vote); err != nil {
+			return err
+		}
+	} else if err := unindexVoteOption(ctx, &vote); err != nil {
+		return err
+	}
+
+	// The snapshot is recomputed from storage, which does not see this
+	// transaction's write, so it is adjusted for the resolved vote instead
+	if election.Status == StatusClosed && vote.counted() {
+		if err := addToTallySnapshot(ctx, election, &vote); err != nil {
+			return err
+		}
+	}
+	if err := logEvent(ctx, electionID, EventProvisionalResolved, commitmentHash); err != nil {
+		return err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	return emitEvent(ctx, EventProvisionalResolved, ProvisionalResolvedEvent{
+		ElectionID:     electionID,
+		CommitmentHash: commitmentHash,
+		Status:         status,
+		Reason:         reason,
+		OfficialID:     officialID,
+		TxID:           ctx.GetStub().GetTxID(),
+		Timestamp:      now.Format(time.RFC3339Nano),
+	})
+}
+
+// ListProvisionalVotes returns the provisional votes of an election still
+// awaiting review. Only election officials may call it.
+func (c *BallotContract) ListProvisionalVotes(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) ([]VoteCommitment, error) {
+	if err := requireMSP(ctx, officialMSPs, "election official"); err != nil {
+		return nil, err
+	}
+	if _, err := getElection(ctx, electionID); err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(provisionalVoteIndex, []string{electionID})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	votes := []VoteCommitment{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(record.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) != 2 {
+			continue
+		}
+
+		key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{electionID, parts[1]})
+		if err != nil {
+			return nil, err
+		}
+		bytes, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, err
+		}
+		if bytes == nil {
+			continue
+		}
+
+		var vote VoteCommitment
+		if err := unmarshalState(bytes, &vote); err != nil {
+			return nil, err
+		}
+		if vote.ProvisionalStatus != ProvisionalPending || vote.Invalidated {
+			continue
+		}
+		votes = append(votes, vote)
+	}
+
+	return votes, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestGetVotesByOptionExcludesUnconfirmedProvisionalVotes(t *testing.T) {
+	env := newTestEnv(t)
+	env.ctx.SetStub(&paginatingStub{MockStub: env.stub})
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true,"maxVotesPerOption":1}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	for i, subject := range []string{"pending", "rejected", "confirmed"} {
+		env.mustInvoke(func() error {
+			return env.contract.CastProvisionalVote(env.ctx, "e1", subject, testHash(i+2), "yes", "{}")
+		})
+	}
+	env.mustInvoke(func() error {
+		return env.contract.RejectProvisionalVote(env.ctx, "e1", testHash(3), "not eligible", "official1")
+	})
+	env.mustInvoke(func() error {
+		return env.contract.ConfirmProvisionalVote(env.ctx, "e1", testHash(4), "official1")
+	})
+
+	var page *VotePage
+	env.mustInvoke(func() (err error) {
+		page, err = env.contract.GetVotesByOption(env.ctx, "e1", "yes", 10, "")
+		return err
+	})
+	listed := map[string]bool{}
+	for _, vote := range page.Votes {
+		listed[vote.SubjectHash] = true
+	}
+	if len(listed) != 2 || !listed["subject1"] || !listed["confirmed"] {
+		t.Fatalf("got votes for %v, want subject1 and confirmed", listed)
+	}
+
+	var anomalous []VoteCommitment
+	env.mustInvoke(func() (err error) {
+		anomalous, err = env.contract.GetAnomalousVotes(env.ctx, "e1")
+		return err
+	})
+	if len(anomalous) != 1 || anomalous[0].SubjectHash != "confirmed" {
+		t.Fatalf("got anomalous votes %+v, want only the confirmed vote past the limit", anomalous)
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// proxyDelegatorIndex lists the subjects who delegated their vote to a proxy.
+const proxyDelegatorIndex = "proxy~delegator"
+
+// delegationKey holds the proxy a subject delegated their vote to.
+func delegationKey(electionID, subjectHash string) string {
+	return fmt.Sprintf("delegation:%s:%s", electionID, subjectHash)
+}
+
+// voter returns the subject a vote counts for: the delegator of a vote cast by
+// a proxy, and otherwise the subject who cast it.
+func (v *VoteCommitment) voter() string {
+	if v.DelegatedFrom != "" {
+		return v.DelegatedFrom
+	}
+	return v.SubjectHash
+}
+
+// RegisterProxy delegates a subject's vote to another subject, the proxy.
+// When the proxy votes, a vote with the same choice is also recorded for each
+// of their delegators, with DelegatedFrom set and weighted by the delegator's
+// weight, and the delegators are marked as having voted. A delegator may not
+// vote directly once delegated. Delegation goes one hop only: a proxy may not
+// have delegated their own vote and a delegator may not be a proxy. Neither
+// subject may have voted yet, and both must be registered unless the election
+// allows unregistered subjects. Sealed elections do not support delegation,
+// since the votes recorded for delegators could not be revealed.
+func (c *BallotContract) RegisterProxy(
+	ctx contractapi.TransactionContextInterface,
+	electionID, subjectHash, proxySubjectHash string,
+) error {
+	if err := validateInputs(
+		requireKeyID("election ID", electionID),
+		requireKeyID("subject hash", subjectHash),
+		requireKeyID("proxy subject hash", proxySubjectHash),
+	); err != nil {
+		return err
+	}
+	if subjectHash == proxySubjectHash {
+		return codedErrorf(ErrCodeInvalidArgument, "a subject cannot be their own proxy")
+	}
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	switch election.Status {
+	case StatusDraft, StatusRegistration, StatusOpen, StatusPaused:
+	default:
+		return codedErrorf(ErrCodeInvalidStatus, "votes cannot be delegated while election %s is %s", electionID, election.Status)
+	}
+	if election.Sealed {
+		return codedErrorf(ErrCodeInvalidStatus, "election %s is sealed; votes cannot be delegated", electionID)
+	}
+
+	for _, subject := range []string{subjectHash, proxySubjectHash} {
+		if !election.AllowUnregistered {
+			registered, err := ctx.GetStub().GetState(subjectKey(electionID, subject))
+			if err != nil {
+				return err
+			}
+			if registered == nil {
+				return codedErrorf(ErrCodeNotRegistered, "subject %s not registered", subject)
+			}
+		}
+		voted, err := ctx.GetStub().GetState(votedKey(electionID, subject))
+		if err != nil {
+			return err
+		}
+		if voted != nil {
+			return codedErrorf(ErrCodeAlreadyVoted, "subject %s has already voted", subject)
+		}
+	}
+
+	delegated, err := ctx.GetStub().GetState(delegationKey(electionID, subjectHash))
+	if err != nil {
+		return err
+	}
+	if delegated != nil {
+		return codedErrorf(ErrCodeAlreadyExists, "subject %s has already delegated their vote", subjectHash)
+	}
+	proxyDelegated, err := ctx.GetStub().GetState(delegationKey(electionID, proxySubjectHash))
+	if err != nil {
+		return err
+	}
+	if proxyDelegated != nil {
+		return codedErrorf(ErrCodeInvalidArgument, "proxy %s has delegated their own vote; delegation chains are not allowed", proxySubjectHash)
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(proxyDelegatorIndex, []string{electionID, subjectHash})
+	if err != nil {
+		return err
+	}
+	isProxy := iterator.HasNext()
+	iterator.Close()
+	if isProxy {
+		return codedErrorf(ErrCodeInvalidArgument, "subject %s is a proxy for other subjects; delegation chains are not allowed", subjectHash)
+	}
+
+	if err := ctx.GetStub().PutState(delegationKey(electionID, subjectHash), []byte(proxySubjectHash)); err != nil {
+		return err
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(proxyDelegatorIndex, []string{electionID, proxySubjectHash, subjectHash})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(indexKey, []byte{0x00})
+}
+
+// delegatedVotes returns the votes a proxy's vote carries for the subjects who
+// delegated to the proxy, together with the delegators not yet marked as
+// having voted. Each vote copies the proxy's choice under the commitment hash
+// digest(proxyCommitmentHash|delegatorSubjectHash).
+func delegatedVotes(
+	ctx contractapi.TransactionContextInterface,
+	election *Election,
+	proxyVote *VoteCommitment,
+) ([]*VoteCommitment, []string, error) {
+	electionID := election.ElectionID
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(proxyDelegatorIndex, []string{electionID, proxyVote.SubjectHash})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iterator.Close()
+
+	votes := []*VoteCommitment{}
+	newVoters := []string{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(record.Key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(parts) != 3 {
+			continue
+		}
+		delegator := parts[2]
+
+		vote := &VoteCommitment{
+			ElectionID:     electionID,
+			ContestID:      proxyVote.ContestID,
+			SubjectHash:    proxyVote.SubjectHash,
+			CommitmentHash: election.digest(proxyVote.CommitmentHash + "|" + delegator),
+			OptionID:       proxyVote.OptionID,
+			Preferences:    proxyVote.Preferences,
+			WriteIn:        proxyVote.WriteIn,
+			WriteInText:    proxyVote.WriteInText,
+			Meta:           proxyVote.Meta,
+			DelegatedFrom:  delegator,
+		}
+		if vote.Weight, err = subjectWeight(ctx, election, delegator); err != nil {
+			return nil, nil, err
+		}
+		key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{electionID, vote.CommitmentHash})
+		if err != nil {
+			return nil, nil, err
+		}
+		exists, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if exists != nil {
+			return nil, nil, codedErrorf(ErrCodeDuplicateCommitment, "commitment already exists for delegator %s", delegator)
+		}
+
+		voted, err := ctx.GetStub().GetState(votedKey(electionID, delegator))
+		if err != nil {
+			return nil, nil, err
+		}
+		if voted == nil {
+			newVoters = append(newVoters, delegator)
+		}
+		votes = append(votes, vote)
+	}
+	return votes, newVoters, nil
+}
+
+// proxyDelegators returns the subjects who delegated their vote to a proxy.
+func proxyDelegators(ctx contractapi.TransactionContextInterface, electionID, proxySubjectHash string) ([]string, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(proxyDelegatorIndex, []string{electionID, proxySubjectHash})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	delegators := []string{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(record.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) == 3 {
+			delegators = append(delegators, parts[2])
+		}
+	}
+	return delegators, nil
+}
+
+// markDelegatorsVoted sets the has-voted markers of delegators whose votes a
+// proxy's vote recorded.
+func markDelegatorsVoted(ctx contractapi.TransactionContextInterface, electionID string, delegators []string) error {
+	for _, delegator := range delegators {
+		if err := ctx.GetStub().PutState(votedKey(electionID, delegator), []byte(ctx.GetStub().GetTxID())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putDelegatedVote stores a vote recorded for a delegator with its commitment,
+// option and anomaly index entries, listing it in the subject index under the
+// delegator, whose has-voted marker it sets.
+func putDelegatedVote(ctx contractapi.TransactionContextInterface, vote *VoteCommitment) error {
+	key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{vote.ElectionID, vote.CommitmentHash})
+	if err != nil {
+		return err
+	}
+	bytes, err := marshalState(vote)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, bytes); err != nil {
+		return err
+	}
+
+	indexes := [][]string{
+		{voteCommitmentIndex, vote.CommitmentHash, vote.ElectionID},
+		{voteSubjectIndex, vote.ElectionID, vote.DelegatedFrom, vote.CommitmentHash},
+	}
+	if vote.Anomalous {
+		indexes = append(indexes, []string{anomalousVoteIndex, vote.ElectionID, vote.CommitmentHash})
+	}
+	for _, index := range indexes {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(index[0], index[1:])
+		if err != nil {
+			return err
+		}
+		if err := ctx.GetStub().PutState(indexKey, []byte{0x00}); err != nil {
+			return err
+		}
+	}
+	return indexVoteOption(ctx, vote)
+}
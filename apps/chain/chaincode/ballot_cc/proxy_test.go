@@ -0,0 +1,208 @@
+package main
+
+import "testing"
+
+func TestProxyVoteCountsForDelegators(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	for _, delegator := range []string{"subject1", "subject2"} {
+		env.mustInvoke(func() error {
+			return env.contract.RegisterProxy(env.ctx, "e1", delegator, "proxy")
+		})
+	}
+	env.castVote("e1", "proxy", testHash(1), "yes")
+	env.castVote("e1", "subject3", testHash(2), "no")
+
+	var tally *Tally
+	var count int
+	env.mustInvoke(func() (err error) {
+		if tally, err = env.contract.TallyResults(env.ctx, "e1", "", false); err != nil {
+			return err
+		}
+		count, err = env.contract.GetVoteCount(env.ctx, "e1")
+		return err
+	})
+	if tally.Counts["yes"] != 3 || tally.Counts["no"] != 1 {
+		t.Fatalf("got counts %v, want 3 yes and 1 no", tally.Counts)
+	}
+	if count != 4 {
+		t.Fatalf("got vote count %d, want 4", count)
+	}
+
+	delegated := map[string]bool{}
+	for _, vote := range scanVotes(env, "e1") {
+		if vote.DelegatedFrom != "" {
+			if vote.SubjectHash != "proxy" || vote.OptionID != "yes" {
+				t.Errorf("got delegated vote %+v", vote)
+			}
+			delegated[vote.DelegatedFrom] = true
+		}
+	}
+	if len(delegated) != 2 || !delegated["subject1"] || !delegated["subject2"] {
+		t.Fatalf("got delegated votes for %v, want subject1 and subject2", delegated)
+	}
+}
+
+func TestProxyVoteCarriesDelegatorWeight(t *testing.T) {
+	env := newTestEnv(t)
+	env.createElection("e1", `{"title":"Board","options":["yes","no"],"weighted":true}`)
+	for subject, weight := range map[string]int{"proxy": 1, "subject1": 5} {
+		env.mustInvoke(func() error {
+			return env.contract.RegisterWeightedSubject(env.ctx, "e1", subject, weight)
+		})
+	}
+	env.mustInvoke(func() error {
+		return env.contract.RegisterProxy(env.ctx, "e1", "subject1", "proxy")
+	})
+	env.mustInvoke(func() error {
+		return env.contract.OpenElection(env.ctx, "e1")
+	})
+	env.castVote("e1", "proxy", testHash(1), "yes")
+
+	var tally *Tally
+	env.mustInvoke(func() (err error) {
+		tally, err = env.contract.TallyResults(env.ctx, "e1", "", false)
+		return err
+	})
+	if tally.Counts["yes"] != 6 {
+		t.Fatalf("got %d weighted yes votes, want 6", tally.Counts["yes"])
+	}
+}
+
+func TestDelegatorCannotVoteDirectly(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.mustInvoke(func() error {
+		return env.contract.RegisterProxy(env.ctx, "e1", "subject1", "proxy")
+	})
+
+	err := env.invoke(func() error {
+		return env.contract.CastVote(env.ctx, "e1", "subject1", testHash(1), "no", "{}")
+	})
+	wantCode(t, err, ErrCodeVoteDelegated)
+
+	env.castVote("e1", "proxy", testHash(2), "yes")
+	err = env.invoke(func() error {
+		return env.contract.CastVote(env.ctx, "e1", "subject1", testHash(3), "no", "{}")
+	})
+	if code := errorCode(err); code != ErrCodeVoteDelegated && code != ErrCodeAlreadyVoted {
+		t.Fatalf("got error %v, want a delegated or already voted error", err)
+	}
+}
+
+func TestProxyChainsRejected(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.mustInvoke(func() error {
+		return env.contract.RegisterProxy(env.ctx, "e1", "subject1", "proxy")
+	})
+
+	err := env.invoke(func() error {
+		return env.contract.RegisterProxy(env.ctx, "e1", "proxy", "other")
+	})
+	wantCode(t, err, ErrCodeInvalidArgument)
+
+	err = env.invoke(func() error {
+		return env.contract.RegisterProxy(env.ctx, "e1", "subject2", "subject1")
+	})
+	wantCode(t, err, ErrCodeInvalidArgument)
+
+	err = env.invoke(func() error {
+		return env.contract.RegisterProxy(env.ctx, "e1", "subject1", "other")
+	})
+	wantCode(t, err, ErrCodeAlreadyExists)
+}
+
+// scanVotes returns every vote recorded in an election.
+func scanVotes(env *testEnv, electionID string) []VoteCommitment {
+	env.t.Helper()
+	votes := []VoteCommitment{}
+	env.mustInvoke(func() error {
+		iterator, err := env.ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{electionID})
+		if err != nil {
+			return err
+		}
+		defer iterator.Close()
+		for iterator.HasNext() {
+			record, err := iterator.Next()
+			if err != nil {
+				return err
+			}
+			var vote VoteCommitment
+			if err := unmarshalState(record.Value, &vote); err != nil {
+				return err
+			}
+			votes = append(votes, vote)
+		}
+		return nil
+	})
+	return votes
+}
+
+func TestDelegatedVoteListedUnderDelegator(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.mustInvoke(func() error {
+		return env.contract.RegisterProxy(env.ctx, "e1", "subject1", "proxy")
+	})
+	env.castVote("e1", "proxy", testHash(1), "yes")
+
+	var delegated, own []VoteCommitment
+	var status *SubjectStatus
+	env.mustInvoke(func() (err error) {
+		if delegated, err = env.contract.GetVotesBySubject(env.ctx, "e1", "subject1", true); err != nil {
+			return err
+		}
+		if own, err = env.contract.GetVotesBySubject(env.ctx, "e1", "proxy", true); err != nil {
+			return err
+		}
+		status, err = env.contract.GetSubjectStatus(env.ctx, "e1", "subject1")
+		return err
+	})
+	if len(delegated) != 1 || delegated[0].DelegatedFrom != "subject1" || delegated[0].OptionID != "yes" {
+		t.Fatalf("got votes %+v for the delegator, want the vote their proxy cast", delegated)
+	}
+	if !status.HasVoted || status.VotedTxID != delegated[0].TxID {
+		t.Fatalf("got subject status %+v, want it to agree with vote %s", status, delegated[0].TxID)
+	}
+	if len(own) != 1 || own[0].CommitmentHash != testHash(1) {
+		t.Fatalf("got votes %+v for the proxy, want only their own", own)
+	}
+}
+
+func TestInvalidateProxyVoteInvalidatesDelegatedVotes(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.mustInvoke(func() error {
+		return env.contract.RegisterProxy(env.ctx, "e1", "subject1", "proxy")
+	})
+	env.castVote("e1", "proxy", testHash(1), "yes")
+
+	err := env.invoke(func() error {
+		return env.contract.InvalidateVote(env.ctx, "e1", "subject1", "coercion", "official1")
+	})
+	wantCode(t, err, ErrCodeNotFound)
+
+	env.mustInvoke(func() error {
+		return env.contract.InvalidateVote(env.ctx, "e1", "proxy", "coercion", "official1")
+	})
+	var delegated []VoteCommitment
+	var status *SubjectStatus
+	var count int
+	env.mustInvoke(func() (err error) {
+		if delegated, err = env.contract.GetVotesBySubject(env.ctx, "e1", "subject1", false); err != nil {
+			return err
+		}
+		if status, err = env.contract.GetSubjectStatus(env.ctx, "e1", "subject1"); err != nil {
+			return err
+		}
+		count, err = env.contract.GetVoteCount(env.ctx, "e1")
+		return err
+	})
+	if len(delegated) != 1 || !delegated[0].Invalidated {
+		t.Fatalf("got votes %+v for the delegator, want their delegated vote invalidated", delegated)
+	}
+	if status.HasVoted || count != 0 {
+		t.Fatalf("got subject status %+v and vote count %d, want the delegator cleared", status, count)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// RankedRound is the state of one instant-runoff counting round.
+type RankedRound struct {
+	Round      int            `json:"round"`
+	Counts     map[string]int `json:"counts"`
+	Exhausted  int            `json:"exhausted"`
+	Eliminated string         `json:"eliminated,omitempty"`
+}
+
+// RankedTally is the round-by-round result of an instant-runoff count.
+type RankedTally struct {
+	ElectionID string        `json:"electionId"`
+	Rounds     []RankedRound `json:"rounds"`
+	Winner     string        `json:"winner,omitempty"`
+}
+
+// validatePreferences checks that a ranking lists distinct election options.
+func validatePreferences(election *Election, preferences []string) error {
+	seen := map[string]bool{}
+	for _, option := range preferences {
+		if !election.hasOption(option) {
+			return codedErrorf(ErrCodeInvalidOption, "invalid option for election: %s", option)
+		}
+		if seen[option] {
+			return codedErrorf(ErrCodeInvalidOption, "option ranked more than once: %s", option)
+		}
+		seen[option] = true
+	}
+	return nil
+}
+
+// CastRankedVote records a preferential vote. preferencesJSON is a JSON array
+// of option IDs, most preferred first. OptionID is set to the first preference
+// so single-choice readers keep working.
+func (c *BallotContract) CastRankedVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID, subjectHash, commitmentHash, preferencesJSON, metaJSON string,
+) error {
+	if err := validateInputs(
+		maxLength("preferences", preferencesJSON, maxJSONLength),
+		maxLength("metadata", metaJSON, maxJSONLength),
+	); err != nil {
+		return err
+	}
+
+	var preferences []string
+	if err := json.Unmarshal([]byte(preferencesJSON), &preferences); err != nil {
+		return withCode(ErrCodeInvalidArgument, err)
+	}
+	if len(preferences) == 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "at least one preference is required")
+	}
+
+	var meta map[string]any
+	if err := json.Unmarshal([]byte(metaJSON), &meta); err != nil {
+		return withCode(ErrCodeInvalidArgument, err)
+	}
+
+	return recordVote(ctx, &VoteCommitment{
+		ElectionID:     electionID,
+		SubjectHash:    subjectHash,
+		CommitmentHash: commitmentHash,
+		OptionID:       preferences[0],
+		Preferences:    preferences,
+		Meta:           meta,
+	})
+}
+
+// TallyRankedResults runs an instant-runoff count over the election's votes.
+// Each round counts every ballot for its highest-ranked continuing option; an
+// option with more than half of the continuing ballots wins, otherwise the
+// option with the fewest votes is eliminated. Ties for elimination are broken
+// by the fewest votes in the most recent earlier round where the tied options
+// differ, and failing that the option that sorts last by OptionID is
+// eliminated. Ballots with no continuing preference are counted as exhausted.
+// Single-choice votes count as a ranking of one.
+func (c *BallotContract) TallyRankedResults(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*RankedTally, error) {
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{electionID})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	ballots := [][]string{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var vote VoteCommitment
+		if err := unmarshalState(record.Value, &vote); err != nil {
+			return nil, err
+		}
+		if !vote.counted() || vote.OptionID == AbstainOptionID {
+			continue
+		}
+		if len(vote.Preferences) > 0 {
+			ballots = append(ballots, vote.Preferences)
+		} else {
+			ballots = append(ballots, []string{vote.OptionID})
+		}
+	}
+
+	return runInstantRunoff(electionID, election.Options, ballots), nil
+}
+
+// runInstantRunoff counts ranked ballots over the given options.
+func runInstantRunoff(electionID string, options []string, ballots [][]string) *RankedTally {
+	tally := &RankedTally{ElectionID: electionID, Rounds: []RankedRound{}}
+
+	continuing := map[string]bool{}
+	for _, option := range options {
+		continuing[option] = true
+	}
+
+	for round := 1; len(continuing) > 0; round++ {
+		current := RankedRound{Round: round, Counts: map[string]int{}}
+		for option := range continuing {
+			current.Counts[option] = 0
+		}
+
+		active := 0
+		for _, ballot := range ballots {
+			counted := false
+			for _, option := range ballot {
+				if continuing[option] {
+					current.Counts[option]++
+					counted = true
+					break
+				}
+			}
+			if counted {
+				active++
+			} else {
+				current.Exhausted++
+			}
+		}
+
+		if active == 0 {
+			tally.Rounds = append(tally.Rounds, current)
+			return tally
+		}
+
+		for option, count := range current.Counts {
+			if count*2 > active || len(continuing) == 1 {
+				tally.Rounds = append(tally.Rounds, current)
+				tally.Winner = option
+				return tally
+			}
+		}
+
+		current.Eliminated = eliminationCandidate(current.Counts, tally.Rounds)
+		delete(continuing, current.Eliminated)
+		tally.Rounds = append(tally.Rounds, current)
+	}
+
+	return tally
+}
+
+// eliminationCandidate picks the option to eliminate from a round's counts,
+// applying the tie-breaks documented on TallyRankedResults.
+func eliminationCandidate(counts map[string]int, previous []RankedRound) string {
+	lowest := -1
+	for _, count := range counts {
+		if lowest < 0 || count < lowest {
+			lowest = count
+		}
+	}
+
+	tied := []string{}
+	for option, count := range counts {
+		if count == lowest {
+			tied = append(tied, option)
+		}
+	}
+
+	for i := len(previous) - 1; i >= 0 && len(tied) > 1; i-- {
+		fewest := -1
+		for _, option := range tied {
+			if count := previous[i].Counts[option]; fewest < 0 || count < fewest {
+				fewest = count
+			}
+		}
+		narrowed := []string{}
+		for _, option := range tied {
+			if previous[i].Counts[option] == fewest {
+				narrowed = append(narrowed, option)
+			}
+		}
+		tied = narrowed
+	}
+
+	sort.Strings(tied)
+	return tied[len(tied)-1]
+}
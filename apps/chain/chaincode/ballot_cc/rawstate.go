@@ -0,0 +1,55 @@
+package main
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetRawState returns the value stored under any ledger key exactly as
+// written, for inspecting state during incident response. key is the full
+// state key, including the null-byte separators of composite keys. Only
+// callers from an administrator organization may use it. Every call emits a
+// "RawStateRead" event naming the key and caller; the event is only recorded
+// on the ledger when the call is submitted rather than evaluated.
+func (c *BallotContract) GetRawState(ctx contractapi.TransactionContextInterface, key string) (string, error) {
+	if err := validateInputs(
+		requireID("key", key),
+	); err != nil {
+		return "", err
+	}
+	if err := requireMSP(ctx, adminMSPs, "administrator"); err != nil {
+		return "", err
+	}
+
+	mspID, err := clientMSP(ctx)
+	if err != nil {
+		return "", err
+	}
+	id, err := clientID(ctx)
+	if err != nil {
+		return "", err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := emitEvent(ctx, EventRawStateRead, RawStateReadEvent{
+		Key:       key,
+		MSPID:     mspID,
+		ClientID:  id,
+		TxID:      ctx.GetStub().GetTxID(),
+		Timestamp: now.Format(time.RFC3339Nano),
+	}); err != nil {
+		return "", err
+	}
+
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", err
+	}
+	if bytes == nil {
+		return "", codedErrorf(ErrCodeNotFound, "no state stored under key %q", key)
+	}
+	return string(bytes), nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func resultsKey(electionID string) string {
+	return fmt.Sprintf("results:%s", electionID)
+}
+
+// ResultsHistoryEntry is one version of an election's certified results.
+type ResultsHistoryEntry struct {
+	TxID      string          `json:"txId"`
+	Timestamp string          `json:"timestamp"`
+	Results   *ElectionResult `json:"results,omitempty"`
+}
+
+// GetResults returns the certified results of an election, or the latest
+// amendment of them, in which case Amended is set.
+func (c *BallotContract) GetResults(ctx contractapi.TransactionContextInterface, electionID string) (*ElectionResult, error) {
+	bytes, err := ctx.GetStub().GetState(resultsKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	if bytes == nil {
+		return nil, fmt.Errorf("election %s results not certified", electionID)
+	}
+
+	var results ElectionResult
+	if err := unmarshalState(bytes, &results); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+// AmendResults supersedes the certified results of a CERTIFIED election, for
+// example after a recount. The previous certification stays on the ledger and
+// is returned by GetResultsHistory. Like CertifyResults it is restricted to
+// allowed certifier organizations. The results key keeps the endorsement
+// policy set at certification, so the amendment must be endorsed by the
+// certifying organization. Emits a "ResultsAmended" event.
+func (c *BallotContract) AmendResults(
+	ctx contractapi.TransactionContextInterface,
+	electionID, newResultsHash string,
+	totalVotes int,
+	reason, certifierID string,
+) error {
+	if err := validateInputs(
+		requireHash("results hash", newResultsHash),
+		requireID("certifier ID", certifierID),
+		maxLength("reason", reason, maxTextLength),
+	); err != nil {
+		return err
+	}
+	if reason == "" {
+		return codedErrorf(ErrCodeInvalidArgument, "amendment reason is required")
+	}
+	if totalVotes < 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "total votes must not be negative")
+	}
+	if err := requireCertifier(ctx); err != nil {
+		return err
+	}
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if election.Status != StatusCertified {
+		return codedErrorf(ErrCodeInvalidStatus, "election %s is %s; only CERTIFIED results can be amended", electionID, election.Status)
+	}
+
+	previous, err := c.GetResults(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	results := *previous
+	results.ResultsHash = newResultsHash
+	results.TotalVotes = totalVotes
+	results.CertifiedAt = now.Format(time.RFC3339Nano)
+	results.CertifierID = certifierID
+	results.Amended = true
+	results.AmendmentReason = reason
+	results.PreviousResultsHash = previous.ResultsHash
+	results.Winner = ""
+	results.Ties = nil
+
+	bytes, err := marshalState(results)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(resultsKey(electionID), bytes); err != nil {
+		return err
+	}
+	if err := logEvent(ctx, electionID, EventResultsAmended, newResultsHash); err != nil {
+		return err
+	}
+
+	return emitEvent(ctx, EventResultsAmended, ResultsAmendedEvent{
+		ElectionID:          electionID,
+		ResultsHash:         newResultsHash,
+		PreviousResultsHash: previous.ResultsHash,
+		TotalVotes:          totalVotes,
+		Reason:              reason,
+		CertifierID:         certifierID,
+	})
+}
+
+// GetResultsHistory returns every version of an election's certified results,
+// newest first: each amendment followed by the original certification.
+func (c *BallotContract) GetResultsHistory(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) ([]ResultsHistoryEntry, error) {
+	iterator, err := ctx.GetStub().GetHistoryForKey(resultsKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	history := []ResultsHistoryEntry{}
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		entry := ResultsHistoryEntry{TxID: modification.GetTxId()}
+		if ts := modification.GetTimestamp(); ts != nil {
+			entry.Timestamp = time.Unix(ts.GetSeconds(), int64(ts.GetNanos())).UTC().Format(time.RFC3339Nano)
+		}
+		if !modification.GetIsDelete() {
+			var results ElectionResult
+			if err := unmarshalState(modification.GetValue(), &results); err != nil {
+				return nil, err
+			}
+			entry.Results = &results
+		}
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
+
+// lockResults sets a key-level endorsement policy on the results key so that
+// any later write must be endorsed by a peer of the certifying organization.
+// The peer enforces this at validation time, independently of chaincode logic.
+func lockResults(ctx contractapi.TransactionContextInterface, key string) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get certifier MSP ID: %w", err)
+	}
+
+	ep, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return err
+	}
+	if err := ep.AddOrgs(statebased.RoleTypePeer, mspID); err != nil {
+		return err
+	}
+	policy, err := ep.Policy()
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().SetStateValidationParameter(key, policy)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+)
+
+// closeElection closes an election and returns the results hash of its tally
+// snapshot.
+func (env *testEnv) closeElection(electionID string) string {
+	env.t.Helper()
+	env.mustInvoke(func() error {
+		return env.contract.CloseElection(env.ctx, electionID)
+	})
+	var hash string
+	env.mustInvoke(func() (err error) {
+		hash, err = env.contract.ComputeResultsHash(env.ctx, electionID)
+		return err
+	})
+	return hash
+}
+
+// certify closes an election and certifies its results.
+func (env *testEnv) certify(electionID string, totalVotes int) {
+	env.t.Helper()
+	hash := env.closeElection(electionID)
+	env.mustInvoke(func() error {
+		return env.contract.CertifyResults(env.ctx, electionID, hash, totalVotes, "2026-01-01T00:00:00Z", "certifier1", "")
+	})
+}
+
+func TestCertifiedElectionRejectsSubmissions(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	env.certify("e1", 1)
+
+	err := env.invoke(func() error {
+		return env.contract.CastVote(env.ctx, "e1", "subject2", testHash(2), "no", "{}")
+	})
+	wantCode(t, err, ErrCodeElectionNotOpen)
+	if !strings.Contains(err.Error(), "CERTIFIED") {
+		t.Errorf("error %q does not name the CERTIFIED status", err)
+	}
+
+	err = env.invoke(func() error {
+		return env.contract.SubmitBallotCommitment(env.ctx, "e1", "ballot1", testHash(3), "", "")
+	})
+	wantCode(t, err, ErrCodeElectionNotOpen)
+}
+
+func TestCertifyResultsRejectsSecondCertification(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	env.certify("e1", 1)
+
+	var hash string
+	env.mustInvoke(func() (err error) {
+		hash, err = env.contract.ComputeResultsHash(env.ctx, "e1")
+		return err
+	})
+	err := env.invoke(func() error {
+		return env.contract.CertifyResults(env.ctx, "e1", hash, 1, "2026-01-02T00:00:00Z", "certifier1", "")
+	})
+	wantCode(t, err, ErrCodeInvalidStatus)
+	if !strings.Contains(err.Error(), "already CERTIFIED") {
+		t.Errorf("error %q does not say the election is already CERTIFIED", err)
+	}
+}
+
+func TestGetResultsReturnsCertifiedResults(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	env.castVote("e1", "subject2", testHash(2), "yes")
+	env.certify("e1", 2)
+
+	var hash string
+	var results *ElectionResult
+	env.mustInvoke(func() (err error) {
+		if hash, err = env.contract.ComputeResultsHash(env.ctx, "e1"); err != nil {
+			return err
+		}
+		results, err = env.contract.GetResults(env.ctx, "e1")
+		return err
+	})
+	if results.ElectionID != "e1" || results.ResultsHash != hash || results.TotalVotes != 2 || results.CertifierID != "certifier1" {
+		t.Fatalf("got results %+v", results)
+	}
+}
+
+func TestGetResultsNotCertified(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	env.closeElection("e1")
+
+	err := env.invoke(func() error {
+		_, err := env.contract.GetResults(env.ctx, "e1")
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "not certified") {
+		t.Fatalf("got error %v, want a not certified error", err)
+	}
+}
+
+// resultsEndorsers returns the organizations the key-level endorsement policy
+// of an election's results key requires.
+func (env *testEnv) resultsEndorsers(electionID string) []string {
+	env.t.Helper()
+	policy, err := env.stub.GetStateValidationParameter(resultsKey(electionID))
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	if policy == nil {
+		return nil
+	}
+	ep, err := statebased.NewStateEP(policy)
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	return ep.ListOrgs()
+}
+
+func TestCertifyResultsLocksResultsToCertifierOrg(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	if orgs := env.resultsEndorsers("e1"); orgs != nil {
+		t.Fatalf("results key has endorsement policy %v before certification", orgs)
+	}
+
+	env.certify("e1", 1)
+	want := []string{"ElectionCommissionMSP"}
+	if orgs := env.resultsEndorsers("e1"); !reflect.DeepEqual(orgs, want) {
+		t.Fatalf("got endorsing orgs %v, want %v", orgs, want)
+	}
+
+	env.mustInvoke(func() error {
+		return env.contract.AmendResults(env.ctx, "e1", testHash(2), 1, "recount", "certifier1")
+	})
+	if orgs := env.resultsEndorsers("e1"); !reflect.DeepEqual(orgs, want) {
+		t.Fatalf("got endorsing orgs %v after amendment, want %v", orgs, want)
+	}
+}
+
+// certifiedQuorum certifies an election of four registered subjects, votes
+// votes and the given quorum and returns its results.
+func certifiedQuorum(t *testing.T, quorum string, votes int) *ElectionResult {
+	t.Helper()
+	env := newTestEnv(t)
+	env.openWithSubjects("e1", `{"title":"Board","options":["yes","no"],"quorumThreshold":`+quorum+`}`,
+		"subject1", "subject2", "subject3", "subject4")
+	for i := 1; i <= votes; i++ {
+		env.castVote("e1", fmt.Sprintf("subject%d", i), testHash(i), "yes")
+	}
+	env.certify("e1", votes)
+
+	var results *ElectionResult
+	env.mustInvoke(func() (err error) {
+		results, err = env.contract.GetResults(env.ctx, "e1")
+		return err
+	})
+	return results
+}
+
+func TestCertifyResultsQuorumMet(t *testing.T) {
+	results := certifiedQuorum(t, "50", 2)
+	if !results.QuorumMet || results.QuorumThreshold != 50 || results.TurnoutPercent != 50 {
+		t.Fatalf("got quorum threshold %v, turnout %v, met %v; want 50, 50, true",
+			results.QuorumThreshold, results.TurnoutPercent, results.QuorumMet)
+	}
+}
+
+func TestCertifyResultsQuorumNotMet(t *testing.T) {
+	results := certifiedQuorum(t, "50", 1)
+	if results.QuorumMet || results.TurnoutPercent != 25 {
+		t.Fatalf("got turnout %v, met %v; want 25, false", results.TurnoutPercent, results.QuorumMet)
+	}
+}
+
+func TestCertifyResultsWithoutQuorum(t *testing.T) {
+	results := certifiedQuorum(t, "0", 1)
+	if !results.QuorumMet {
+		t.Fatal("an election without a quorum did not meet it")
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// checkSealedVote checks a vote cast in a sealed election: it must carry no
+// option, ranking or write-in, and in a multi-contest election must name one
+// of the contests.
+func (e *Election) checkSealedVote(vote *VoteCommitment) error {
+	if vote.OptionID != "" || len(vote.Preferences) > 0 || vote.WriteIn {
+		return codedErrorf(ErrCodeInvalidOption, "election %s is sealed; cast the commitment without an option and reveal it with RevealVote", e.ElectionID)
+	}
+	if len(e.Contests) > 0 || vote.ContestID != "" {
+		if vote.ContestID == "" {
+			return codedErrorf(ErrCodeInvalidOption, "contest ID is required for election %s", e.ElectionID)
+		}
+		if e.contest(vote.ContestID) == nil {
+			return codedErrorf(ErrCodeInvalidOption, "unknown contest %s for election %s", vote.ContestID, e.ElectionID)
+		}
+	}
+	return nil
+}
+
+// revealHash returns the hex digest of "optionID|salt" under the election's
+// hash algorithm, which a sealed vote's commitment hash must equal.
+func (e *Election) revealHash(optionID, salt string) string {
+	return e.digest(optionID + "|" + salt)
+}
+
+// RevealVote records the option of a sealed vote. The commitment hash must be
+// the digest of "optionID|salt" under the election's hash algorithm and the
+// option must be valid for the vote's contest. Votes may be revealed while the
+// election is OPEN, PAUSED or CLOSED; revealing one after closing adds it to
+// the tally snapshot so certification covers it. Emits a "VoteRevealed" event.
+func (c *BallotContract) RevealVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID, commitmentHash, optionID, salt string,
+) error {
+	if err := validateInputs(
+		requireKeyID("election ID", electionID),
+		requireHash("commitment hash", commitmentHash),
+		requireID("option ID", optionID),
+		requireReason("salt", salt),
+	); err != nil {
+		return err
+	}
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if !election.Sealed {
+		return codedErrorf(ErrCodeInvalidStatus, "election %s is not sealed", electionID)
+	}
+	switch election.Status {
+	case StatusOpen, StatusPaused, StatusClosed:
+	default:
+		return codedErrorf(ErrCodeInvalidStatus, "votes cannot be revealed while election %s is %s", electionID, election.Status)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{electionID, commitmentHash})
+	if err != nil {
+		return err
+	}
+	bytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return err
+	}
+	if bytes == nil {
+		return codedErrorf(ErrCodeNotFound, "vote %s not found in election %s", commitmentHash, electionID)
+	}
+
+	var vote VoteCommitment
+	if err := unmarshalState(bytes, &vote); err != nil {
+		return err
+	}
+	if !vote.Sealed {
+		return codedErrorf(ErrCodeInvalidStatus, "vote %s is not sealed", commitmentHash)
+	}
+	if vote.Revealed {
+		return codedErrorf(ErrCodeInvalidStatus, "vote %s is already revealed", commitmentHash)
+	}
+	if vote.Invalidated {
+		return codedErrorf(ErrCodeInvalidStatus, "vote %s has been invalidated", commitmentHash)
+	}
+	if vote.Expired {
+		return codedErrorf(ErrCodeInvalidStatus, "vote %s has expired unrevealed", commitmentHash)
+	}
+	if !strings.EqualFold(election.revealHash(optionID, salt), vote.CommitmentHash) {
+		return codedErrorf(ErrCodeRevealMismatch, "option and salt do not match commitment %s", commitmentHash)
+	}
+
+	vote.OptionID = optionID
+	if err := election.checkVoteOption(&vote); err != nil {
+		return err
+	}
+	vote.Revealed = true
+	vote.RevealSalt = salt
+	bytes, err = marshalState(vote)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, bytes); err != nil {
+		return err
+	}
+	if err := indexVoteOption(ctx, &vote); err != nil {
+		return err
+	}
+
+	if election.Status == StatusClosed && vote.counted() {
+		if err := addToTallySnapshot(ctx, election, &vote); err != nil {
+			return err
+		}
+	}
+
+	return emitSubmissionEvent(ctx, EventVoteRevealed, electionID, commitmentHash)
+}
+
+// maxExpireBatch bounds how many votes one ExpireStaleCommitments transaction
+// expires, keeping its write set within peer limits.
+const maxExpireBatch = 500
+
+// ExpiryResult reports the progress of ExpireStaleCommitments.
+type ExpiryResult struct {
+	Expired  int  `json:"expired"`
+	Complete bool `json:"complete"`
+}
+
+// ExpireStaleCommitments marks the sealed votes of an election that have
+// stayed unrevealed for longer than its RevealTTLSeconds, measured from the
+// vote's RecordedAt to the transaction timestamp, as Expired. Expired votes
+// are kept for audit but cannot be revealed or counted, and their subjects
+// remain recorded as having voted. Votes recorded without a RecordedAt are
+// left alone. Each call expires at most maxExpireBatch votes; call it again
+// until Complete is set. The election must be sealed, have a reveal TTL and
+// be OPEN, PAUSED or CLOSED. Only callers from an administrator organization
+// may call it.
+func (c *BallotContract) ExpireStaleCommitments(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*ExpiryResult, error) {
+	if err := requireMSP(ctx, adminMSPs, "administrator"); err != nil {
+		return nil, err
+	}
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if !election.Sealed {
+		return nil, codedErrorf(ErrCodeInvalidStatus, "election %s is not sealed", electionID)
+	}
+	if election.RevealTTLSeconds == 0 {
+		return nil, codedErrorf(ErrCodeInvalidStatus, "election %s has no reveal TTL", electionID)
+	}
+	switch election.Status {
+	case StatusOpen, StatusPaused, StatusClosed:
+	default:
+		return nil, codedErrorf(ErrCodeInvalidStatus, "commitments cannot be expired while election %s is %s", electionID, election.Status)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := now.Add(-time.Duration(election.RevealTTLSeconds) * time.Second)
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{electionID})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	result := &ExpiryResult{Complete: true}
+	for iterator.HasNext() {
+		if result.Expired == maxExpireBatch {
+			result.Complete = false
+			break
+		}
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var vote VoteCommitment
+		if err := unmarshalState(record.Value, &vote); err != nil {
+			return nil, err
+		}
+		if !vote.Sealed || vote.Revealed || vote.Expired || vote.Invalidated || vote.RecordedAt == "" {
+			continue
+		}
+		recordedAt, err := time.Parse(time.RFC3339Nano, vote.RecordedAt)
+		if err != nil || recordedAt.After(cutoff) {
+			continue
+		}
+
+		vote.Expired = true
+		bytes, err := marshalState(vote)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.GetStub().PutState(record.Key, bytes); err != nil {
+			return nil, err
+		}
+		result.Expired++
+	}
+
+	if result.Expired > 0 {
+		if err := logEvent(ctx, electionID, EventLogCommitmentsExpired); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
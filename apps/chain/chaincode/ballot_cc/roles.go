@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// adminKey holds the client identity ID of the contract administrator, set
+// once by InitLedger. certifiersKey holds the JSON list of MSP IDs allowed to
+// certify results.
+const (
+	adminKey      = "config:admin"
+	certifiersKey = "config:certifiers"
+)
+
+// roleAttribute is the enrollment certificate attribute naming an identity's
+// role within its organization. Identities whose role is observerRole are
+// read-only: they may query aggregate data such as results, counts and audit
+// anchors, but are never authorized for a role, even in an allowed
+// organization.
+const (
+	roleAttribute = "role"
+	observerRole  = "observer"
+)
+
+// auditorMSPs are the organizations whose members may read individual votes,
+// including how a subject voted.
+var auditorMSPs = map[string]bool{
+	"AuditorMSP":            true,
+	"ElectionCommissionMSP": true,
+}
+
+// officialMSPs are the organizations whose members may act on individual
+// votes, such as invalidating one for a supervised re-vote.
+var officialMSPs = map[string]bool{
+	"ElectionCommissionMSP": true,
+}
+
+// adminMSPs are the organizations whose members may use operator tools that
+// bypass the contract's data model, such as GetRawState.
+var adminMSPs = map[string]bool{
+	"ElectionCommissionMSP": true,
+}
+
+// clientMSP returns the MSP ID of the identity that submitted the transaction.
+func clientMSP(ctx contractapi.TransactionContextInterface) (string, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSP ID: %w", err)
+	}
+	return mspID, nil
+}
+
+// clientID returns the unique ID of the identity that submitted the
+// transaction, derived from its certificate subject and issuer.
+func clientID(ctx contractapi.TransactionContextInterface) (string, error) {
+	id, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client identity: %w", err)
+	}
+	return id, nil
+}
+
+// requireAdmin returns an error unless the caller is the identity that
+// initialized the contract.
+func requireAdmin(ctx contractapi.TransactionContextInterface) error {
+	admin, err := ctx.GetStub().GetState(adminKey)
+	if err != nil {
+		return err
+	}
+	if admin == nil {
+		return codedErrorf(ErrCodeInvalidStatus, "contract is not initialized; call InitLedger first")
+	}
+	id, err := clientID(ctx)
+	if err != nil {
+		return err
+	}
+	if id != string(admin) {
+		return codedErrorf(ErrCodeUnauthorized, "caller is not the contract administrator")
+	}
+	return nil
+}
+
+// InitLedger records the calling identity as the contract administrator. It
+// is meant to be invoked once when the chaincode is first deployed, and fails
+// if an administrator is already set.
+func (c *BallotContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
+	admin, err := ctx.GetStub().GetState(adminKey)
+	if err != nil {
+		return err
+	}
+	if admin != nil {
+		return codedErrorf(ErrCodeAlreadyExists, "contract already initialized")
+	}
+
+	id, err := clientID(ctx)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(adminKey, []byte(id))
+}
+
+// SetAllowedCertifiers replaces the list of MSP IDs whose members may certify
+// and amend results. mspsJSON is a JSON array of MSP IDs. Only the contract
+// administrator may call it.
+func (c *BallotContract) SetAllowedCertifiers(ctx contractapi.TransactionContextInterface, mspsJSON string) error {
+	if err := validateInputs(maxLength("certifier MSPs", mspsJSON, maxJSONLength)); err != nil {
+		return err
+	}
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	var msps []string
+	if err := json.Unmarshal([]byte(mspsJSON), &msps); err != nil {
+		return withCode(ErrCodeInvalidArgument, err)
+	}
+	seen := map[string]bool{}
+	certifiers := []string{}
+	for _, msp := range msps {
+		if err := validateInputs(requireID("certifier MSP ID", msp)); err != nil {
+			return err
+		}
+		if !seen[msp] {
+			seen[msp] = true
+			certifiers = append(certifiers, msp)
+		}
+	}
+	sort.Strings(certifiers)
+
+	bytes, err := marshalState(certifiers)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(certifiersKey, bytes)
+}
+
+// GetAllowedCertifiers returns the MSP IDs allowed to certify results. It is
+// empty until SetAllowedCertifiers is first called, while only election
+// officials may certify.
+func (c *BallotContract) GetAllowedCertifiers(ctx contractapi.TransactionContextInterface) ([]string, error) {
+	certifiers, _, err := allowedCertifiers(ctx)
+	return certifiers, err
+}
+
+// allowedCertifiers returns the stored certifier list and whether one has
+// been set.
+func allowedCertifiers(ctx contractapi.TransactionContextInterface) ([]string, bool, error) {
+	bytes, err := ctx.GetStub().GetState(certifiersKey)
+	if err != nil {
+		return nil, false, err
+	}
+	if bytes == nil {
+		return []string{}, false, nil
+	}
+
+	var certifiers []string
+	if err := json.Unmarshal(bytes, &certifiers); err != nil {
+		return nil, false, err
+	}
+	return certifiers, true, nil
+}
+
+// requireCertifier returns an error unless the caller belongs to an allowed
+// certifier organization. Until a certifier list is set, only election
+// officials may certify.
+func requireCertifier(ctx contractapi.TransactionContextInterface) error {
+	certifiers, set, err := allowedCertifiers(ctx)
+	if err != nil {
+		return err
+	}
+	if !set {
+		return requireMSP(ctx, officialMSPs, "certifier")
+	}
+
+	allowed := map[string]bool{}
+	for _, msp := range certifiers {
+		allowed[msp] = true
+	}
+	return requireMSP(ctx, allowed, "certifier")
+}
+
+// isAuthorizedRole reports whether the caller belongs to one of the allowed
+// organizations and is not an observer identity.
+func isAuthorizedRole(ctx contractapi.TransactionContextInterface, allowed map[string]bool) (bool, error) {
+	mspID, err := clientMSP(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !allowed[mspID] {
+		return false, nil
+	}
+
+	role, found, err := ctx.GetClientIdentity().GetAttributeValue(roleAttribute)
+	if err != nil {
+		return false, fmt.Errorf("failed to read client role attribute: %w", err)
+	}
+	return !found || role != observerRole, nil
+}
+
+// requireMSP returns an error unless isAuthorizedRole authorizes the caller.
+// role names the permission in the error message.
+func requireMSP(ctx contractapi.TransactionContextInterface, allowed map[string]bool, role string) error {
+	authorized, err := isAuthorizedRole(ctx, allowed)
+	if err != nil {
+		return err
+	}
+	if !authorized {
+		mspID, err := clientMSP(ctx)
+		if err != nil {
+			return err
+		}
+		if allowed[mspID] {
+			return codedErrorf(ErrCodeUnauthorized, "observer identities are not authorized as %s", role)
+		}
+		return codedErrorf(ErrCodeUnauthorized, "caller MSP %s is not an authorized %s", mspID, role)
+	}
+	return nil
+}
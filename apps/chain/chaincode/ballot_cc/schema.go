@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// contractVersion is the chaincode release. Bump it with every release that
+// is installed on peers.
+const contractVersion = "1.1.0"
+
+// currentSchemaVersion is the schema version stamped on every record this
+// chaincode writes. Bump it whenever a record's stored layout changes in a way
+// older readers would misinterpret, and teach unmarshalState the upgrade.
+const currentSchemaVersion = 1
+
+// Record is embedded in every persisted record type to carry its schema
+// version. Records written before versioning was introduced have none and
+// are read as version 0.
+type Record struct {
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+}
+
+func (r Record) recordVersion() int { return r.SchemaVersion }
+
+func (r *Record) setRecordVersion(version int) { r.SchemaVersion = version }
+
+// versionedRecord is implemented by every type that embeds Record.
+type versionedRecord interface {
+	recordVersion() int
+}
+
+// unmarshalState decodes a stored record and upgrades it to the current
+// schema version. It refuses records written by a newer chaincode, which this
+// version cannot be sure to interpret correctly.
+func unmarshalState(data []byte, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	record, ok := v.(interface {
+		versionedRecord
+		setRecordVersion(int)
+	})
+	if !ok {
+		return nil
+	}
+	version := record.recordVersion()
+	if version > currentSchemaVersion {
+		return fmt.Errorf("record schema version %d is newer than supported version %d; upgrade the chaincode", version, currentSchemaVersion)
+	}
+	// Version 0 records share version 1's layout: every field added since is
+	// optional, so they need no changes beyond the version stamp.
+	record.setRecordVersion(currentSchemaVersion)
+	return nil
+}
+
+// ContractVersion identifies the running chaincode.
+type ContractVersion struct {
+	Version       string `json:"version"`
+	SchemaVersion int    `json:"schemaVersion"`
+}
+
+// GetContractVersion returns the chaincode release and the record schema
+// version it writes, so operators can check peers during a rolling upgrade.
+func (c *BallotContract) GetContractVersion(ctx contractapi.TransactionContextInterface) (*ContractVersion, error) {
+	return &ContractVersion{
+		Version:       contractVersion,
+		SchemaVersion: currentSchemaVersion,
+	}, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// voterKeyKey maps a voter public key fingerprint to the subject that signed
+// with it in an election, so one key cannot vouch for several subjects.
+func voterKeyKey(electionID, fingerprint string) string {
+	return fmt.Sprintf("voterkey:%s:%s", electionID, fingerprint)
+}
+
+// voteSigningPayload is the message a voter signs: the election ID,
+// commitment hash and option ID joined by "|".
+func voteSigningPayload(vote *VoteCommitment) []byte {
+	return []byte(vote.ElectionID + "|" + vote.CommitmentHash + "|" + vote.OptionID)
+}
+
+// parseVoterPubKey parses a PEM or base64 DER encoded PKIX public key and
+// returns it with its fingerprint, the hex SHA-256 of the DER encoding.
+func parseVoterPubKey(encoded string) (any, string, error) {
+	var der []byte
+	if block, _ := pem.Decode([]byte(encoded)); block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "", codedErrorf(ErrCodeInvalidSignature, "voter public key must be PEM or base64 DER")
+		}
+		der = decoded
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, "", codedErrorf(ErrCodeInvalidSignature, "invalid voter public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return key, hex.EncodeToString(sum[:]), nil
+}
+
+// verifyVoterSignature checks a vote's base64 signature against its public
+// key and sets VoterKeyFingerprint. ECDSA signatures are ASN.1 encoded over
+// the SHA-256 of the payload; Ed25519 signatures are over the payload itself.
+func verifyVoterSignature(vote *VoteCommitment) error {
+	key, fingerprint, err := parseVoterPubKey(vote.VoterPubKey)
+	if err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(vote.VoterSignature)
+	if err != nil {
+		return codedErrorf(ErrCodeInvalidSignature, "voter signature must be base64")
+	}
+
+	payload := voteSigningPayload(vote)
+	valid := false
+	switch pub := key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(payload)
+		valid = ecdsa.VerifyASN1(pub, digest[:], signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(pub, payload, signature)
+	default:
+		return codedErrorf(ErrCodeInvalidSignature, "unsupported voter public key type %T", key)
+	}
+	if !valid {
+		return codedErrorf(ErrCodeInvalidSignature, "invalid voter signature")
+	}
+
+	vote.VoterKeyFingerprint = fingerprint
+	return nil
+}
+
+// CastSignedVote behaves like CastVote but carries the voter's signature over
+// "electionID|commitmentHash|optionID". voterPubKey is a PEM or base64 DER
+// PKIX ECDSA or Ed25519 public key and voterSignature the base64 signature.
+// The vote is rejected unless the signature verifies, and a key that already
+// signed for another subject in the election is refused.
+func (c *BallotContract) CastSignedVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID, subjectHash, commitmentHash, optionID, metaJSON, voterPubKey, voterSignature string,
+) error {
+	if err := validateInputs(
+		maxLength("metadata", metaJSON, maxJSONLength),
+		maxLength("voter public key", voterPubKey, maxTextLength),
+		maxLength("voter signature", voterSignature, maxTextLength),
+	); err != nil {
+		return err
+	}
+	if voterPubKey == "" || voterSignature == "" {
+		return codedErrorf(ErrCodeInvalidSignature, "voter public key and signature are required")
+	}
+
+	var meta map[string]any
+	if err := json.Unmarshal([]byte(metaJSON), &meta); err != nil {
+		return withCode(ErrCodeInvalidArgument, err)
+	}
+
+	return recordVote(ctx, &VoteCommitment{
+		ElectionID:     electionID,
+		SubjectHash:    subjectHash,
+		CommitmentHash: commitmentHash,
+		OptionID:       optionID,
+		Meta:           meta,
+		VoterPubKey:    voterPubKey,
+		VoterSignature: voterSignature,
+	})
+}
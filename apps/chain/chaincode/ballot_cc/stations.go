@@ -0,0 +1,158 @@
+package main
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const stationCloseObjectType = "stationclose"
+
+// StationClose is a polling station's closing record: the hash of its local
+// ballot box and the number of ballots it holds, as reported at poll close.
+type StationClose struct {
+	Record
+
+	ElectionID  string `json:"electionId"`
+	StationID   string `json:"stationId"`
+	ClosingHash string `json:"closingHash"`
+	BallotCount int    `json:"ballotCount"`
+	Timestamp   string `json:"timestamp"`
+	RecordedAt  string `json:"recordedAt"`
+	TxID        string `json:"txId"`
+}
+
+// StationReconciliation compares the ballot counts reported by an election's
+// polling stations with the number of ballot commitments on the ledger.
+type StationReconciliation struct {
+	ElectionID     string `json:"electionId"`
+	Stations       int    `json:"stations"`
+	StationBallots int    `json:"stationBallots"`
+	LedgerBallots  int    `json:"ledgerBallots"`
+	Matches        bool   `json:"matches"`
+}
+
+// AnchorStationClose records a polling station's closing hash and ballot
+// count for an election that is OPEN, PAUSED or CLOSED. Each station may close
+// only once.
+func (c *BallotContract) AnchorStationClose(
+	ctx contractapi.TransactionContextInterface,
+	electionID, stationID, closingHash string,
+	ballotCount int,
+	timestamp string,
+) error {
+	if err := validateInputs(
+		requireKeyID("election ID", electionID),
+		requireID("station ID", stationID),
+		requireDigest("closing hash", closingHash, HashSHA256),
+		maxLength("timestamp", timestamp, maxIDLength),
+	); err != nil {
+		return err
+	}
+	if ballotCount < 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "ballot count must not be negative")
+	}
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	switch election.Status {
+	case StatusOpen, StatusPaused, StatusClosed:
+	default:
+		return codedErrorf(ErrCodeInvalidStatus, "stations cannot close while election %s is %s", electionID, election.Status)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(stationCloseObjectType, []string{electionID, stationID})
+	if err != nil {
+		return err
+	}
+	exists, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return err
+	}
+	if exists != nil {
+		return codedErrorf(ErrCodeAlreadyExists, "station %s has already closed in election %s", stationID, electionID)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	bytes, err := marshalState(StationClose{
+		ElectionID:  electionID,
+		StationID:   stationID,
+		ClosingHash: closingHash,
+		BallotCount: ballotCount,
+		Timestamp:   timestamp,
+		RecordedAt:  now.Format(time.RFC3339Nano),
+		TxID:        ctx.GetStub().GetTxID(),
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, bytes)
+}
+
+// GetStationCloses returns the closing records of an election's polling
+// stations, ordered by station ID.
+func (c *BallotContract) GetStationCloses(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) ([]StationClose, error) {
+	if _, err := getElection(ctx, electionID); err != nil {
+		return nil, err
+	}
+	return getStationCloses(ctx, electionID)
+}
+
+func getStationCloses(ctx contractapi.TransactionContextInterface, electionID string) ([]StationClose, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(stationCloseObjectType, []string{electionID})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	closes := []StationClose{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var stationClose StationClose
+		if err := unmarshalState(record.Value, &stationClose); err != nil {
+			return nil, err
+		}
+		closes = append(closes, stationClose)
+	}
+	return closes, nil
+}
+
+// ReconcileStationCloses sums the ballot counts of an election's station
+// closes and compares the total with the ledger's ballot commitment count
+// (see GetBallotCommitmentCount), spoiled ballots included.
+func (c *BallotContract) ReconcileStationCloses(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*StationReconciliation, error) {
+	ballots, err := c.GetBallotCommitmentCount(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	closes, err := getStationCloses(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	reconciliation := &StationReconciliation{
+		ElectionID:    electionID,
+		Stations:      len(closes),
+		LedgerBallots: ballots.Total,
+	}
+	for _, stationClose := range closes {
+		reconciliation.StationBallots += stationClose.BallotCount
+	}
+	reconciliation.Matches = reconciliation.StationBallots == reconciliation.LedgerBallots
+	return reconciliation, nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// SubjectStatus reports a subject's registration and voting state without
+// revealing the ballot contents.
+type SubjectStatus struct {
+	Registered   bool   `json:"registered"`
+	RegisteredAt string `json:"registeredAt,omitempty"`
+	HasVoted     bool   `json:"hasVoted"`
+	VotedTxID    string `json:"votedTxId,omitempty"`
+}
+
+// subjectKey holds the RFC3339 time a subject registered. Markers written
+// before the time was recorded hold "registered".
+func subjectKey(electionID, subjectHash string) string {
+	return fmt.Sprintf("subject:%s:%s", electionID, subjectHash)
+}
+
+// votedKey holds the ID of the transaction that recorded a subject's vote.
+// Markers written before the transaction ID was recorded hold "voted".
+func votedKey(electionID, subjectHash string) string {
+	return fmt.Sprintf("voted:%s:%s", electionID, subjectHash)
+}
+
+// requireRegistrationOpen loads an election and returns an error unless
+// subjects may currently register for it.
+func requireRegistrationOpen(ctx contractapi.TransactionContextInterface, electionID string) (*Election, error) {
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if election.Status != StatusDraft && election.Status != StatusRegistration {
+		return nil, codedErrorf(ErrCodeRegistrationClosed, "registration is not open for election %s (status %s)", electionID, election.Status)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := election.checkRegistrationWindow(now); err != nil {
+		return nil, err
+	}
+	return election, nil
+}
+
+// registerSubject idempotently registers a subject, counting it towards the
+// election's registered total, and reports whether a new registration was
+// written.
+func registerSubject(ctx contractapi.TransactionContextInterface, electionID, subjectHash string) (bool, error) {
+	created, err := putSubject(ctx, electionID, subjectHash)
+	if err != nil || !created {
+		return created, err
+	}
+	return true, addToCounter(ctx, registeredCountKey(electionID), 1)
+}
+
+// putSubject writes a subject's registration marker unless it already exists
+// and reports whether it was written. It does not update the registered count.
+func putSubject(ctx contractapi.TransactionContextInterface, electionID, subjectHash string) (bool, error) {
+	if err := validateInputs(requireKeyID("subject hash", subjectHash)); err != nil {
+		return false, err
+	}
+
+	key := subjectKey(electionID, subjectHash)
+	exists, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, err
+	}
+	if exists != nil {
+		return false, nil
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	return true, ctx.GetStub().PutState(key, []byte(now.Format(time.RFC3339Nano)))
+}
+
+// registeredAt returns the registration time held by a subject marker, and
+// false for markers that predate recording it.
+func registeredAt(marker []byte) (time.Time, bool) {
+	at, err := time.Parse(time.RFC3339Nano, string(marker))
+	return at, err == nil
+}
+
+// checkVoteDelay returns an error if a subject whose registration marker is
+// given registered less than the election's MinVoteDelaySeconds before the
+// transaction. Unregistered subjects and markers without a registration time
+// pass.
+func (e *Election) checkVoteDelay(ctx contractapi.TransactionContextInterface, marker []byte) error {
+	if e.MinVoteDelaySeconds == 0 || marker == nil {
+		return nil
+	}
+	at, ok := registeredAt(marker)
+	if !ok {
+		return nil
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if elapsed := now.Sub(at); elapsed < time.Duration(e.MinVoteDelaySeconds)*time.Second {
+		return codedErrorf(ErrCodeVoteTooSoon, "subject registered %s ago; election %s requires %ds before voting",
+			elapsed.Truncate(time.Second), e.ElectionID, e.MinVoteDelaySeconds)
+	}
+	return nil
+}
+
+// RegisterSubjectWithStatus behaves like RegisterSubject but returns true only
+// when a new registration was written, and false when the subject was already
+// registered, so callers can tell genuine registrations from retries.
+func (c *BallotContract) RegisterSubjectWithStatus(
+	ctx contractapi.TransactionContextInterface,
+	electionID, subjectHash string,
+) (bool, error) {
+	if _, err := requireRegistrationOpen(ctx, electionID); err != nil {
+		return false, err
+	}
+	return registerSubject(ctx, electionID, subjectHash)
+}
+
+// maxRegistrationBatch caps how many subjects RegisterSubjects accepts in one
+// transaction, keeping its write set within typical block size limits.
+const maxRegistrationBatch = 1000
+
+// RegistrationResult summarizes a bulk registration.
+type RegistrationResult struct {
+	Registered        int `json:"registered"`
+	AlreadyRegistered int `json:"alreadyRegistered"`
+}
+
+// RegisterSubjects registers a JSON array of subject hashes in a single
+// transaction, for importing a voter roll. Registration is idempotent: hashes
+// already registered, or repeated within the array, are counted as already
+// registered. At most maxRegistrationBatch hashes may be submitted at once.
+func (c *BallotContract) RegisterSubjects(
+	ctx contractapi.TransactionContextInterface,
+	electionID, subjectHashesJSON string,
+) (*RegistrationResult, error) {
+	if err := validateInputs(maxLength("subject hashes", subjectHashesJSON, maxBatchJSONLength)); err != nil {
+		return nil, err
+	}
+
+	var subjectHashes []string
+	if err := json.Unmarshal([]byte(subjectHashesJSON), &subjectHashes); err != nil {
+		return nil, withCode(ErrCodeInvalidArgument, err)
+	}
+	if len(subjectHashes) > maxRegistrationBatch {
+		return nil, codedErrorf(ErrCodeInvalidArgument, "at most %d subjects may be registered per transaction, got %d", maxRegistrationBatch, len(subjectHashes))
+	}
+
+	if _, err := requireRegistrationOpen(ctx, electionID); err != nil {
+		return nil, err
+	}
+
+	// Writes made earlier in this transaction are not visible to GetState, so
+	// repeats within the array are tracked in memory and the registered count
+	// is updated once at the end.
+	result := &RegistrationResult{}
+	seen := map[string]bool{}
+	for _, subjectHash := range subjectHashes {
+		if seen[subjectHash] {
+			result.AlreadyRegistered++
+			continue
+		}
+		seen[subjectHash] = true
+
+		created, err := putSubject(ctx, electionID, subjectHash)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			result.Registered++
+		} else {
+			result.AlreadyRegistered++
+		}
+	}
+
+	if result.Registered > 0 {
+		if err := addToCounter(ctx, registeredCountKey(electionID), result.Registered); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// GetSubjectStatus reports whether a subject is registered for an election and
+// whether, and in which transaction, they have voted.
+func (c *BallotContract) GetSubjectStatus(
+	ctx contractapi.TransactionContextInterface,
+	electionID, subjectHash string,
+) (*SubjectStatus, error) {
+	registered, err := ctx.GetStub().GetState(subjectKey(electionID, subjectHash))
+	if err != nil {
+		return nil, err
+	}
+
+	voted, err := ctx.GetStub().GetState(votedKey(electionID, subjectHash))
+	if err != nil {
+		return nil, err
+	}
+
+	status := &SubjectStatus{
+		Registered: registered != nil,
+		HasVoted:   voted != nil,
+	}
+	if at, ok := registeredAt(registered); ok {
+		status.RegisteredAt = at.Format(time.RFC3339Nano)
+	}
+	if voted != nil && string(voted) != "voted" {
+		status.VotedTxID = string(voted)
+	}
+	return status, nil
+}
+
+// IsSubjectRegisteredElsewhere returns the IDs of the elections other than
+// excludeElectionID that a subject is registered for, in election ID order,
+// so applications can enforce that mutually exclusive elections such as a
+// primary and its runoff share no voters. Pass an empty excludeElectionID to
+// list every election. It checks each election on the ledger in turn.
+func (c *BallotContract) IsSubjectRegisteredElsewhere(
+	ctx contractapi.TransactionContextInterface,
+	subjectHash, excludeElectionID string,
+) ([]string, error) {
+	if err := validateInputs(
+		requireKeyID("subject hash", subjectHash),
+		optionalKeyID("excluded election ID", excludeElectionID),
+	); err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByRange("election:", "election;")
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	elections := []string{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		electionID := strings.TrimPrefix(record.Key, "election:")
+		if electionID == excludeElectionID {
+			continue
+		}
+
+		registered, err := ctx.GetStub().GetState(subjectKey(electionID, subjectHash))
+		if err != nil {
+			return nil, err
+		}
+		if registered != nil {
+			elections = append(elections, electionID)
+		}
+	}
+	return elections, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+// openWithSubjects creates an election from configJSON, registers subjects in
+// it and opens it.
+func (env *testEnv) openWithSubjects(electionID, configJSON string, subjectHashes ...string) {
+	env.t.Helper()
+	env.createElection(electionID, configJSON)
+	for _, subjectHash := range subjectHashes {
+		env.mustInvoke(func() error {
+			return env.contract.RegisterSubject(env.ctx, electionID, subjectHash)
+		})
+	}
+	env.mustInvoke(func() error {
+		return env.contract.OpenElection(env.ctx, electionID)
+	})
+}
+
+func TestRegisteredSubjectMayVote(t *testing.T) {
+	env := newTestEnv(t)
+	env.openWithSubjects("e1", `{"title":"Board","options":["yes","no"]}`, "subject1")
+
+	env.castVote("e1", "subject1", testHash(1), "yes")
+}
+
+func TestUnregisteredSubjectRejected(t *testing.T) {
+	env := newTestEnv(t)
+	env.openWithSubjects("e1", `{"title":"Board","options":["yes","no"]}`, "subject1")
+
+	err := env.invoke(func() error {
+		return env.contract.CastVote(env.ctx, "e1", "subject2", testHash(1), "yes", "{}")
+	})
+	wantCode(t, err, ErrCodeNotRegistered)
+}
+
+func TestSubjectRegisteredInOtherElectionRejected(t *testing.T) {
+	env := newTestEnv(t)
+	env.openWithSubjects("e1", `{"title":"Board","options":["yes","no"]}`, "subject1")
+	env.openWithSubjects("e2", `{"title":"Budget","options":["yes","no"]}`)
+
+	err := env.invoke(func() error {
+		return env.contract.CastVote(env.ctx, "e2", "subject1", testHash(1), "yes", "{}")
+	})
+	wantCode(t, err, ErrCodeNotRegistered)
+}
+
+func TestAllowUnregisteredElectionAcceptsUnregisteredSubject(t *testing.T) {
+	env := newTestEnv(t)
+	env.createElection("e1", `{"title":"Board","options":["yes","no"]}`)
+	env.mustInvoke(func() error {
+		return env.contract.SetAllowUnregistered(env.ctx, "e1", true)
+	})
+	env.mustInvoke(func() error {
+		return env.contract.OpenElection(env.ctx, "e1")
+	})
+
+	env.castVote("e1", "subject1", testHash(1), "yes")
+}
+
+func TestSetRegistrationWindowRequiresOfficial(t *testing.T) {
+	env := newTestEnv(t)
+	env.createElection("e1", `{"title":"Board","options":["yes","no"]}`)
+	env.mustInvoke(func() error {
+		return env.contract.SetRegistrationWindow(env.ctx, "e1", "2026-01-01T00:00:00Z", "2026-02-01T00:00:00Z")
+	})
+
+	env.setCaller("VoterOrgMSP")
+	err := env.invoke(func() error {
+		return env.contract.SetRegistrationWindow(env.ctx, "e1", "", "")
+	})
+	wantCode(t, err, ErrCodeUnauthorized)
+}
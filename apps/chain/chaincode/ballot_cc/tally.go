@@ -0,0 +1,374 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Tally is the per-option vote count for an election computed from ledger state.
+// Winner and Ties (see decideWinner) are left empty when the tally spans every
+// contest of a multi-contest election.
+type Tally struct {
+	Record
+
+	ElectionID  string         `json:"electionId"`
+	ContestID   string         `json:"contestId,omitempty"`
+	StationID   string         `json:"stationId,omitempty"`
+	Counts      map[string]int `json:"counts"`
+	TotalVotes  int            `json:"totalVotes"`
+	Abstentions int            `json:"abstentions"`
+	Weighted    bool           `json:"weighted,omitempty"`
+	Winner      string         `json:"winner,omitempty"`
+	Ties        []string       `json:"ties,omitempty"`
+}
+
+// decideWinner returns the option with the most votes. When several options
+// share the highest count the winner is the lexicographically smallest option
+// ID, and ties lists all of them in that order. There is no winner when no
+// votes were counted.
+func decideWinner(counts map[string]int) (winner string, ties []string) {
+	best := 0
+	var leaders []string
+	for option, count := range counts {
+		switch {
+		case count > best:
+			best = count
+			leaders = []string{option}
+		case count == best && count > 0:
+			leaders = append(leaders, option)
+		}
+	}
+	if len(leaders) == 0 {
+		return "", nil
+	}
+
+	sort.Strings(leaders)
+	if len(leaders) > 1 {
+		ties = leaders
+	}
+	return leaders[0], ties
+}
+
+// tallyKey is where TallyResults stores a tally; contestID is empty for the
+// election-wide tally.
+func tallyKey(electionID, contestID string) string {
+	if contestID == "" {
+		return fmt.Sprintf("tally:%s", electionID)
+	}
+	return fmt.Sprintf("tally:%s:%s", electionID, contestID)
+}
+
+// TallySnapshot is the tally of an election frozen when it was closed.
+type TallySnapshot struct {
+	Tally
+	ClosedAt string `json:"closedAt"`
+	TxID     string `json:"txId"`
+}
+
+func tallySnapshotKey(electionID string) string {
+	return fmt.Sprintf("snapshot:%s", electionID)
+}
+
+// counted reports whether a vote counts towards tallies: it is not
+// invalidated or expired, is not a sealed vote still to be revealed, and is
+// not a provisional vote awaiting or denied confirmation.
+func (v *VoteCommitment) counted() bool {
+	if v.Invalidated || v.Expired || (v.Sealed && !v.Revealed) {
+		return false
+	}
+	return !v.Provisional || v.ProvisionalStatus == ProvisionalConfirmed
+}
+
+// snapshotTally computes the election's tally and stores it as its snapshot.
+func snapshotTally(ctx contractapi.TransactionContextInterface, electionID string) error {
+	tally, err := computeTally(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	bytes, err := marshalState(TallySnapshot{
+		Tally:    *tally,
+		ClosedAt: now.Format(time.RFC3339Nano),
+		TxID:     ctx.GetStub().GetTxID(),
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(tallySnapshotKey(electionID), bytes)
+}
+
+// getTallySnapshot returns the tally snapshot stored when the election closed.
+func getTallySnapshot(ctx contractapi.TransactionContextInterface, electionID string) (*TallySnapshot, error) {
+	bytes, err := ctx.GetStub().GetState(tallySnapshotKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	if bytes == nil {
+		return nil, fmt.Errorf("election %s has no tally snapshot; close it first", electionID)
+	}
+
+	var snapshot TallySnapshot
+	if err := unmarshalState(bytes, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// addToTallySnapshot counts a vote into the snapshot of a closed election,
+// for votes that become countable after closing. The snapshot keeps its
+// ClosedAt and takes this transaction's ID.
+func addToTallySnapshot(ctx contractapi.TransactionContextInterface, election *Election, vote *VoteCommitment) error {
+	snapshot, err := getTallySnapshot(ctx, election.ElectionID)
+	if err != nil {
+		return err
+	}
+
+	weight := 1
+	if election.Weighted {
+		weight = vote.weight()
+	}
+	snapshot.TotalVotes++
+	if vote.OptionID == AbstainOptionID {
+		snapshot.Abstentions += weight
+	} else {
+		snapshot.Counts[vote.tallyOption()] += weight
+	}
+	if len(election.Contests) == 0 {
+		snapshot.Winner, snapshot.Ties = decideWinner(snapshot.Counts)
+	}
+	snapshot.TxID = ctx.GetStub().GetTxID()
+
+	bytes, err := marshalState(snapshot)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(tallySnapshotKey(election.ElectionID), bytes)
+}
+
+// GetTallySnapshot returns the tally recorded when an election was closed.
+func (c *BallotContract) GetTallySnapshot(ctx contractapi.TransactionContextInterface, electionID string) (*TallySnapshot, error) {
+	return getTallySnapshot(ctx, electionID)
+}
+
+// computeTally scans every vote recorded for an election and counts them by
+// option, skipping votes that are not counted (see counted).
+// Every configured option appears in the counts, even with zero votes; write-ins appear under their synthetic
+// "writein:" option IDs. In a multi-contest election options are counted as
+// "<contestID>/<optionID>". Abstentions are counted in Abstentions rather than
+// Counts, and are included in TotalVotes. In a weighted election the counts
+// and abstentions are sums of vote weights; TotalVotes always counts votes.
+func computeTally(ctx contractapi.TransactionContextInterface, electionID string) (*Tally, error) {
+	return tallyVotes(ctx, electionID, "", nil)
+}
+
+// tallyVotes is computeTally restricted to one contest when contestID is set,
+// counting by plain option ID, that also skips votes for which include, if
+// non-nil, returns false.
+func tallyVotes(
+	ctx contractapi.TransactionContextInterface,
+	electionID, contestID string,
+	include func(*VoteCommitment) bool,
+) (*Tally, error) {
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if contestID != "" && election.contest(contestID) == nil {
+		return nil, fmt.Errorf("unknown contest %s for election %s", contestID, electionID)
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{electionID})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	tally := &Tally{ElectionID: electionID, ContestID: contestID, Weighted: election.Weighted, Counts: map[string]int{}}
+	for _, option := range election.Options {
+		tally.Counts[option] = 0
+	}
+	for _, contest := range election.Contests {
+		for _, option := range contest.Options {
+			if contestID == "" {
+				tally.Counts[contestTallyKey(contest.ContestID, option)] = 0
+			} else if contest.ContestID == contestID {
+				tally.Counts[option] = 0
+			}
+		}
+	}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var vote VoteCommitment
+		if err := unmarshalState(record.Value, &vote); err != nil {
+			return nil, err
+		}
+		if !vote.counted() || (include != nil && !include(&vote)) {
+			continue
+		}
+		if contestID != "" && vote.ContestID != contestID {
+			continue
+		}
+		weight := 1
+		if election.Weighted {
+			weight = vote.weight()
+		}
+		tally.TotalVotes++
+		if vote.OptionID == AbstainOptionID {
+			tally.Abstentions += weight
+			continue
+		}
+		if contestID == "" {
+			tally.Counts[vote.tallyOption()] += weight
+		} else {
+			tally.Counts[vote.OptionID] += weight
+		}
+	}
+
+	if len(election.Contests) == 0 || contestID != "" {
+		tally.Winner, tally.Ties = decideWinner(tally.Counts)
+	}
+	return tally, nil
+}
+
+// TallyResults counts the votes recorded for an election by option. A non-empty
+// contestID restricts the count to that contest of a multi-contest election.
+// When store is true the tally is also written to the ledger so observers can
+// compare it with the certified ResultsHash.
+func (c *BallotContract) TallyResults(
+	ctx contractapi.TransactionContextInterface,
+	electionID, contestID string,
+	store bool,
+) (*Tally, error) {
+	tally, err := tallyVotes(ctx, electionID, contestID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if store {
+		bytes, err := marshalState(tally)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.GetStub().PutState(tallyKey(electionID, contestID), bytes); err != nil {
+			return nil, err
+		}
+	}
+
+	return tally, nil
+}
+
+// PreviewTally computes the current tally of an election, in any status, so
+// dashboards can follow it while voting is still OPEN. It writes nothing and
+// emits no event, and is tagged as an evaluate transaction in the contract
+// metadata.
+func (c *BallotContract) PreviewTally(ctx contractapi.TransactionContextInterface, electionID string) (*Tally, error) {
+	return computeTally(ctx, electionID)
+}
+
+// CountVotesByOption returns the current number of counted votes for each
+// option of an election, computed on the peer so clients need not page
+// through the votes. Configured options with no votes are reported as zero;
+// abstentions are not included (see PreviewTally). Like PreviewTally it writes
+// nothing and is tagged as an evaluate transaction.
+func (c *BallotContract) CountVotesByOption(ctx contractapi.TransactionContextInterface, electionID string) (map[string]int, error) {
+	tally, err := computeTally(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	return tally.Counts, nil
+}
+
+// TallyUncontestedResults counts the votes recorded for an election like
+// TallyResults, but leaves out votes with an OPEN or UPHELD challenge. It is
+// informational; CertifyResults always certifies the full tally.
+func (c *BallotContract) TallyUncontestedResults(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*Tally, error) {
+	contested, err := contestedCommitments(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	return tallyVotes(ctx, electionID, "", func(vote *VoteCommitment) bool {
+		return !contested[vote.CommitmentHash]
+	})
+}
+
+// stationMetaKey is the vote metadata key naming the polling station a vote
+// was cast at.
+const stationMetaKey = "stationId"
+
+// voteStation returns the polling station recorded in a vote's metadata, or
+// "" if it has none. Numeric station IDs are formatted as decimals.
+func voteStation(vote *VoteCommitment) string {
+	switch station := vote.Meta[stationMetaKey].(type) {
+	case string:
+		return station
+	case float64:
+		return strconv.FormatFloat(station, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// TallyByStation counts an election's votes like TallyResults, restricted to
+// those whose metadata names stationID under "stationId", for a partial
+// recount of one polling station. A station with no votes, including one that
+// does not exist, yields zero counts.
+func (c *BallotContract) TallyByStation(
+	ctx contractapi.TransactionContextInterface,
+	electionID, stationID string,
+) (*Tally, error) {
+	if err := requireID("station ID", stationID); err != nil {
+		return nil, err
+	}
+
+	tally, err := tallyVotes(ctx, electionID, "", func(vote *VoteCommitment) bool {
+		return voteStation(vote) == stationID
+	})
+	if err != nil {
+		return nil, err
+	}
+	tally.StationID = stationID
+	return tally, nil
+}
+
+// canonicalResultsHash returns the canonical hash of a tally: the hex SHA-256 of
+// its abstentions and option->count map serialized as compact JSON with keys in
+// sorted order, e.g. {"abstentions":1,"counts":{"optionA":3,"optionB":0}}.
+func canonicalResultsHash(tally *Tally) (string, error) {
+	bytes, err := json.Marshal(struct {
+		Abstentions int            `json:"abstentions"`
+		Counts      map[string]int `json:"counts"`
+	}{tally.Abstentions, tally.Counts})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ComputeResultsHash returns the canonical results hash of the election's
+// tally snapshot, which CertifyResults requires the certifier to match.
+func (c *BallotContract) ComputeResultsHash(ctx contractapi.TransactionContextInterface, electionID string) (string, error) {
+	snapshot, err := getTallySnapshot(ctx, electionID)
+	if err != nil {
+		return "", err
+	}
+	return canonicalResultsHash(&snapshot.Tally)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestComputeResultsHashIsCanonical(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	env.castVote("e1", "subject2", testHash(2), "yes")
+	env.castVote("e1", "subject3", testHash(3), AbstainOptionID)
+	hash := env.closeElection("e1")
+
+	sum := sha256.Sum256([]byte(`{"abstentions":1,"counts":{"no":0,"yes":2}}`))
+	if want := hex.EncodeToString(sum[:]); hash != want {
+		t.Fatalf("got results hash %s, want %s", hash, want)
+	}
+}
+
+func TestCertifyResultsRejectsMismatchedHash(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["yes","no"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "yes")
+	env.closeElection("e1")
+
+	sum := sha256.Sum256([]byte(`{"abstentions":0,"counts":{"no":1,"yes":0}}`))
+	err := env.invoke(func() error {
+		return env.contract.CertifyResults(env.ctx, "e1", hex.EncodeToString(sum[:]), 1, "2026-01-01T00:00:00Z", "certifier1", "")
+	})
+	wantCode(t, err, ErrCodeResultsMismatch)
+
+	err = env.invoke(func() error {
+		_, err := env.contract.GetResults(env.ctx, "e1")
+		return err
+	})
+	if err == nil {
+		t.Fatal("results were stored despite the mismatch")
+	}
+}
+
+func TestCertifiedResultsBreakTieByOptionID(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["zeta","alpha","mid"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "zeta")
+	env.castVote("e1", "subject2", testHash(2), "alpha")
+	env.castVote("e1", "subject3", testHash(3), "mid")
+	env.castVote("e1", "subject4", testHash(4), "zeta")
+	env.castVote("e1", "subject5", testHash(5), "alpha")
+
+	var tally *Tally
+	env.mustInvoke(func() (err error) {
+		tally, err = env.contract.TallyResults(env.ctx, "e1", "", false)
+		return err
+	})
+	want := []string{"alpha", "zeta"}
+	if tally.Winner != "alpha" || !reflect.DeepEqual(tally.Ties, want) {
+		t.Fatalf("got tally winner %q and ties %v, want alpha and %v", tally.Winner, tally.Ties, want)
+	}
+
+	env.certify("e1", 5)
+	var results *ElectionResult
+	env.mustInvoke(func() (err error) {
+		results, err = env.contract.GetResults(env.ctx, "e1")
+		return err
+	})
+	if results.Winner != "alpha" || !reflect.DeepEqual(results.Ties, want) {
+		t.Fatalf("got certified winner %q and ties %v, want alpha and %v", results.Winner, results.Ties, want)
+	}
+}
+
+func TestCertifiedResultsWithoutTie(t *testing.T) {
+	env := newTestEnv(t)
+	env.openElection("e1", `{"title":"Board","options":["zeta","alpha"],"allowUnregistered":true}`)
+	env.castVote("e1", "subject1", testHash(1), "zeta")
+	env.castVote("e1", "subject2", testHash(2), "zeta")
+	env.castVote("e1", "subject3", testHash(3), "alpha")
+	env.certify("e1", 3)
+
+	var results *ElectionResult
+	env.mustInvoke(func() (err error) {
+		results, err = env.contract.GetResults(env.ctx, "e1")
+		return err
+	})
+	if results.Winner != "zeta" || results.Ties != nil {
+		t.Fatalf("got certified winner %q and ties %v, want zeta and no ties", results.Winner, results.Ties)
+	}
+}
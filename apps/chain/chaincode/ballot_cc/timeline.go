@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Timeline event names besides the election statuses an election enters.
+const (
+	TimelineCreated     = "CREATED"
+	TimelineFirstVote   = "FIRST_VOTE"
+	TimelineAuditAnchor = "AUDIT_ANCHOR"
+)
+
+// TimelineEvent is one entry of an election's timeline. Detail carries the
+// Merkle root of audit anchors.
+type TimelineEvent struct {
+	Record
+
+	Event     string `json:"event"`
+	Timestamp string `json:"timestamp"`
+	TxID      string `json:"txId,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// firstVoteKey holds the TimelineEvent of the first vote cast in an election.
+func firstVoteKey(electionID string) string {
+	return fmt.Sprintf("firstvote:%s", electionID)
+}
+
+// recordFirstVote stores the current transaction as the election's first vote.
+// An existing record is kept, since invalidating votes can bring the vote
+// count back to zero.
+func recordFirstVote(ctx contractapi.TransactionContextInterface, electionID string) error {
+	existing, err := ctx.GetStub().GetState(firstVoteKey(electionID))
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return nil
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	bytes, err := marshalState(TimelineEvent{
+		Event:     TimelineFirstVote,
+		Timestamp: now.Format(time.RFC3339Nano),
+		TxID:      ctx.GetStub().GetTxID(),
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(firstVoteKey(electionID), bytes)
+}
+
+// GetElectionTimeline returns an election's key events in chronological
+// order: its creation and each status it entered, its first vote and its
+// audit anchors. Anchors are placed by their client-supplied timestamp.
+// Events from before the timeline was recorded are missing.
+func (c *BallotContract) GetElectionTimeline(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) ([]TimelineEvent, error) {
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []TimelineEvent{}
+	for _, transition := range election.Transitions {
+		event := string(transition.Status)
+		if transition.Status == StatusDraft && len(events) == 0 {
+			event = TimelineCreated
+		}
+		events = append(events, TimelineEvent{Event: event, Timestamp: transition.Timestamp, TxID: transition.TxID, Detail: transition.Reason})
+	}
+
+	firstVote, err := ctx.GetStub().GetState(firstVoteKey(electionID))
+	if err != nil {
+		return nil, err
+	}
+	if firstVote != nil {
+		var event TimelineEvent
+		if err := unmarshalState(firstVote, &event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	anchors, err := c.ListAuditAnchors(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	for _, anchor := range anchors {
+		if anchor.ElectionID != electionID {
+			continue
+		}
+		events = append(events, TimelineEvent{
+			Event:     TimelineAuditAnchor,
+			Timestamp: anchor.Timestamp,
+			TxID:      anchor.TxID,
+			Detail:    anchor.MerkleRoot,
+		})
+	}
+
+	// Events with unparseable timestamps sort last
+	at := func(event TimelineEvent) time.Time {
+		t, err := parseTimestamp(event.Timestamp)
+		if err != nil {
+			return time.Unix(1<<62, 0)
+		}
+		return t
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return at(events[i]).Before(at(events[j]))
+	})
+	return events, nil
+}
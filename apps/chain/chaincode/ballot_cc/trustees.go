@@ -0,0 +1,273 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const shareObjectType = "share"
+
+func combinedSharesKey(electionID string) string {
+	return fmt.Sprintf("combined:%s", electionID)
+}
+
+// TrusteeShare is a trustee's partial decryption of a threshold-encrypted tally.
+type TrusteeShare struct {
+	Record
+
+	ElectionID  string         `json:"electionId"`
+	TrusteeID   string         `json:"trusteeId"`
+	Share       map[string]any `json:"share"`
+	SubmittedAt string         `json:"submittedAt"`
+	TxID        string         `json:"txId"`
+}
+
+// validateTrustees checks an election's trustee list and threshold: trustee
+// IDs must be valid IDs, each must name the client identity that submits its
+// share, and a list needs a threshold between 1 and its length.
+func validateTrustees(trustees map[string]string, threshold int) error {
+	if threshold < 0 {
+		return fmt.Errorf("trustee threshold must not be negative")
+	}
+	if threshold > len(trustees) {
+		return fmt.Errorf("trustee threshold %d exceeds the number of trustees (%d)", threshold, len(trustees))
+	}
+	if len(trustees) > 0 && threshold == 0 {
+		return fmt.Errorf("trustee threshold is required when trustees are set")
+	}
+	for trusteeID, identity := range trustees {
+		if err := requireID("trustee ID", trusteeID); err != nil {
+			return err
+		}
+		if identity == "" {
+			return fmt.Errorf("trustee %s has no client identity", trusteeID)
+		}
+		if err := maxLength("trustee identity", identity, maxTextLength); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetTrustees sets the trustees of a DRAFT election and how many of their
+// decryption shares CombineShares requires. trusteesJSON is a JSON object
+// mapping each trustee ID to the client identity ID that must submit the
+// trustee's decryption share.
+func (c *BallotContract) SetTrustees(ctx contractapi.TransactionContextInterface, electionID, trusteesJSON string, threshold int) error {
+	if err := validateInputs(maxLength("trustees", trusteesJSON, maxJSONLength)); err != nil {
+		return err
+	}
+
+	var trustees map[string]string
+	if err := json.Unmarshal([]byte(trusteesJSON), &trustees); err != nil {
+		return withCode(ErrCodeInvalidArgument, err)
+	}
+	if err := validateInputs(validateTrustees(trustees, threshold)); err != nil {
+		return err
+	}
+
+	election, err := requireElectionDraft(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	election.Trustees = trustees
+	election.TrusteeThreshold = threshold
+	return putElection(ctx, election)
+}
+
+// SubmitDecryptionShare anchors a trustee's decryption share for a CLOSED
+// election. trusteeID must be one of the election's Trustees and the caller
+// must be the client identity listed for it. Each trustee may submit exactly
+// one share per election.
+func (c *BallotContract) SubmitDecryptionShare(
+	ctx contractapi.TransactionContextInterface,
+	electionID, trusteeID, shareJSON string,
+) error {
+	if err := validateInputs(
+		requireID("trustee ID", trusteeID),
+		maxLength("share", shareJSON, maxJSONLength),
+	); err != nil {
+		return err
+	}
+
+	var share map[string]any
+	if err := json.Unmarshal([]byte(shareJSON), &share); err != nil {
+		return withCode(ErrCodeInvalidArgument, err)
+	}
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if election.Status != StatusClosed {
+		return codedErrorf(ErrCodeInvalidStatus, "decryption shares can only be submitted once election %s is CLOSED (status %s)", electionID, election.Status)
+	}
+
+	identity, ok := election.Trustees[trusteeID]
+	if !ok {
+		return codedErrorf(ErrCodeUnauthorized, "%s is not a trustee of election %s", trusteeID, electionID)
+	}
+	caller, err := clientID(ctx)
+	if err != nil {
+		return err
+	}
+	if caller != identity {
+		return codedErrorf(ErrCodeUnauthorized, "caller is not the identity registered for trustee %s", trusteeID)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(shareObjectType, []string{electionID, trusteeID})
+	if err != nil {
+		return err
+	}
+	exists, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return err
+	}
+	if exists != nil {
+		return codedErrorf(ErrCodeAlreadyExists, "trustee %s already submitted a share", trusteeID)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	bytes, err := marshalState(TrusteeShare{
+		ElectionID:  electionID,
+		TrusteeID:   trusteeID,
+		Share:       share,
+		SubmittedAt: now.Format(time.RFC3339Nano),
+		TxID:        ctx.GetStub().GetTxID(),
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, bytes)
+}
+
+// CombinedShares is the set of trustee decryption shares fixed for decrypting
+// an election's tally. Decryption itself happens off-chain over exactly these
+// shares; SharesHash lets anyone check they used the same set.
+type CombinedShares struct {
+	Record
+
+	ElectionID string         `json:"electionId"`
+	Shares     []TrusteeShare `json:"shares"`
+	SharesHash string         `json:"sharesHash"`
+	CombinedAt string         `json:"combinedAt"`
+	TxID       string         `json:"txId"`
+}
+
+// CombineShares fixes the decryption shares submitted for a CLOSED election,
+// once at least the election's TrusteeThreshold trustees have submitted, and
+// returns the combined record. It fails with ERR_REVEAL_NOT_PERMITTED ("reveal
+// not yet permitted") while the transaction timestamp is before the
+// election's RevealNotBefore time. Shares may be combined only once. Only
+// election officials may call it.
+func (c *BallotContract) CombineShares(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*CombinedShares, error) {
+	if err := requireMSP(ctx, officialMSPs, "election official"); err != nil {
+		return nil, err
+	}
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if election.Status != StatusClosed {
+		return nil, codedErrorf(ErrCodeInvalidStatus, "decryption shares can only be combined once election %s is CLOSED (status %s)", electionID, election.Status)
+	}
+	if election.TrusteeThreshold == 0 {
+		return nil, codedErrorf(ErrCodeInvalidStatus, "election %s has no trustees", electionID)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if election.RevealNotBefore != "" {
+		revealAt, err := parseTimestamp(election.RevealNotBefore)
+		if err != nil {
+			return nil, err
+		}
+		if now.Before(revealAt) {
+			return nil, codedErrorf(ErrCodeRevealNotPermitted, "reveal not yet permitted: election %s reveals at %s", electionID, election.RevealNotBefore)
+		}
+	}
+
+	key := combinedSharesKey(electionID)
+	exists, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if exists != nil {
+		return nil, codedErrorf(ErrCodeAlreadyExists, "decryption shares for election %s already combined", electionID)
+	}
+
+	shares, err := c.GetDecryptionShares(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(shares) < election.TrusteeThreshold {
+		return nil, codedErrorf(ErrCodeInvalidStatus, "%d of %d required decryption shares submitted", len(shares), election.TrusteeThreshold)
+	}
+
+	sharesBytes, err := marshalState(shares)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(sharesBytes)
+
+	combined := &CombinedShares{
+		ElectionID: electionID,
+		Shares:     shares,
+		SharesHash: hex.EncodeToString(sum[:]),
+		CombinedAt: now.Format(time.RFC3339Nano),
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	bytes, err := marshalState(combined)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.GetStub().PutState(key, bytes); err != nil {
+		return nil, err
+	}
+	return combined, nil
+}
+
+// GetDecryptionShares lists every decryption share submitted for an election,
+// ordered by trustee ID.
+func (c *BallotContract) GetDecryptionShares(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) ([]TrusteeShare, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(shareObjectType, []string{electionID})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	shares := []TrusteeShare{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var share TrusteeShare
+		if err := unmarshalState(record.Value, &share); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Argument limits. Every write stores its arguments under keys or in values
+// on the ledger, so unbounded inputs would let a client bloat world state.
+const (
+	maxIDLength        = 128
+	maxHashLength      = 128
+	maxTextLength      = 1024
+	maxJSONLength      = 64 * 1024
+	maxBatchJSONLength = 1024 * 1024
+	maxOptions         = 256
+)
+
+// validateInputs returns the first failing check, coded as an invalid
+// argument, so a transaction can list every constraint on its arguments in one
+// call before touching state.
+func validateInputs(checks ...error) error {
+	for _, err := range checks {
+		if err != nil {
+			return withCode(ErrCodeInvalidArgument, err)
+		}
+	}
+	return nil
+}
+
+// requireID checks a required identifier such as an election or ballot ID.
+func requireID(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is required", name)
+	}
+	return maxLength(name, value, maxIDLength)
+}
+
+// requireKeyID checks a required identifier that is embedded in plain ledger
+// keys, such as an election or subject ID. Those keys separate their parts
+// with ':' and range scans end at ';', so an ID holding either could read or
+// overwrite another election's or subject's records.
+func requireKeyID(name, value string) error {
+	if err := requireID(name, value); err != nil {
+		return err
+	}
+	if strings.ContainsAny(value, ":;") {
+		return fmt.Errorf("%s must not contain ':' or ';'", name)
+	}
+	return nil
+}
+
+// optionalID checks an identifier that may be left empty.
+func optionalID(name, value string) error {
+	if value == "" {
+		return nil
+	}
+	return requireID(name, value)
+}
+
+// optionalKeyID checks a key identifier that may be left empty.
+func optionalKeyID(name, value string) error {
+	if value == "" {
+		return nil
+	}
+	return requireKeyID(name, value)
+}
+
+// requireHash checks a required hex-encoded hash such as a commitment hash or
+// Merkle root.
+func requireHash(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is required", name)
+	}
+	if err := maxLength(name, value, maxHashLength); err != nil {
+		return err
+	}
+	if _, err := hex.DecodeString(value); err != nil {
+		return fmt.Errorf("%s must be a hex-encoded hash", name)
+	}
+	return nil
+}
+
+// Commitment hash algorithms an election may be configured with. SHA-256 is
+// the default. Merkle roots are always SHA-256, the hash the audit tree is
+// built with.
+const (
+	HashSHA256 = "SHA-256"
+	HashSHA384 = "SHA-384"
+	HashSHA512 = "SHA-512"
+)
+
+// hashHexLengths is the hex-encoded digest length of each hash algorithm.
+var hashHexLengths = map[string]int{
+	HashSHA256: 64,
+	HashSHA384: 96,
+	HashSHA512: 128,
+}
+
+// validateHashAlgorithm checks a configured hash algorithm; empty selects the
+// default.
+func validateHashAlgorithm(algorithm string) error {
+	if algorithm == "" {
+		return nil
+	}
+	if _, ok := hashHexLengths[algorithm]; !ok {
+		return fmt.Errorf("unsupported hash algorithm %q", algorithm)
+	}
+	return nil
+}
+
+// requireDigest checks a required hex-encoded digest of the given algorithm,
+// rejecting values of the wrong length such as truncated hashes.
+func requireDigest(name, value, algorithm string) error {
+	if err := requireHash(name, value); err != nil {
+		return err
+	}
+	if want := hashHexLengths[algorithm]; len(value) != want {
+		return fmt.Errorf("%s must be %d hex characters for %s, got %d", name, want, algorithm, len(value))
+	}
+	return nil
+}
+
+// maxLength checks that value is at most limit bytes long.
+func maxLength(name, value string, limit int) error {
+	if len(value) > limit {
+		return fmt.Errorf("%s exceeds maximum length of %d bytes", name, limit)
+	}
+	return nil
+}
+
+// AbstainOptionID is the option ID of a deliberate abstention. Every election
+// and contest accepts it; tallies report abstentions separately from the
+// option counts.
+const AbstainOptionID = "ABSTAIN"
+
+// validateOptions checks an election's option list. A repeated option ID
+// would be counted under one tally key, so duplicates are rejected.
+func validateOptions(options []string) error {
+	if len(options) > maxOptions {
+		return fmt.Errorf("elections may have at most %d options, got %d", maxOptions, len(options))
+	}
+	seen := make(map[string]bool, len(options))
+	for i, option := range options {
+		if strings.TrimSpace(option) == "" {
+			return fmt.Errorf("option %d is empty", i+1)
+		}
+		if err := requireID("option ID", option); err != nil {
+			return err
+		}
+		if seen[option] {
+			return fmt.Errorf("duplicate option ID %q", option)
+		}
+		seen[option] = true
+		if strings.HasPrefix(option, writeInOptionPrefix) {
+			return fmt.Errorf("option ID %q uses the reserved write-in prefix", option)
+		}
+		if option == AbstainOptionID {
+			return fmt.Errorf("option ID %q is reserved for abstentions", option)
+		}
+	}
+	return nil
+}
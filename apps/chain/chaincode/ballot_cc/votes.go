@@ -0,0 +1,507 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// subjectVoteKeys returns the storage keys of the votes listed under a subject
+// in an election's subject index: the votes they cast and those a proxy cast
+// for them.
+func subjectVoteKeys(ctx contractapi.TransactionContextInterface, electionID, subjectHash string) ([]string, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteSubjectIndex, []string{electionID, subjectHash})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	keys := []string{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(record.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) != 3 {
+			continue
+		}
+
+		key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{electionID, parts[2]})
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// VotePage is a page of vote commitments returned by paginated queries.
+type VotePage struct {
+	Votes        []VoteCommitment `json:"votes"`
+	Bookmark     string           `json:"bookmark"`
+	FetchedCount int              `json:"fetchedCount"`
+}
+
+// voteNonceKey records the transaction that consumed a client nonce.
+func voteNonceKey(electionID, nonce string) string {
+	return fmt.Sprintf("nonce:%s:%s", electionID, nonce)
+}
+
+// voteTxIndexKey maps a transaction ID to the storage key of the vote it wrote.
+func voteTxIndexKey(txID string) string {
+	return fmt.Sprintf("txidx:%s", txID)
+}
+
+// CastVoteWithNonce behaves like CastVote but also records a client-supplied
+// nonce. Resubmitting a nonce that was already used in the election fails
+// with ERR_DUPLICATE_NONCE, letting the gateway tell an exact replay apart from
+// a genuinely new vote.
+func (c *BallotContract) CastVoteWithNonce(
+	ctx contractapi.TransactionContextInterface,
+	electionID, subjectHash, commitmentHash, optionID, metaJSON, nonce string,
+) error {
+	if err := validateInputs(
+		requireID("nonce", nonce),
+		maxLength("metadata", metaJSON, maxJSONLength),
+	); err != nil {
+		return err
+	}
+
+	var meta map[string]any
+	if err := json.Unmarshal([]byte(metaJSON), &meta); err != nil {
+		return withCode(ErrCodeInvalidArgument, err)
+	}
+
+	return recordVote(ctx, &VoteCommitment{
+		ElectionID:     electionID,
+		SubjectHash:    subjectHash,
+		CommitmentHash: commitmentHash,
+		OptionID:       optionID,
+		Nonce:          nonce,
+		Meta:           meta,
+	})
+}
+
+// ReceiptProof is a vote receipt together with the ledger key it is stored
+// under and the transaction that last wrote it, so clients can fetch and
+// re-hash the raw ledger value independently.
+type ReceiptProof struct {
+	Commitment VoteCommitment `json:"commitment"`
+	StorageKey string         `json:"storageKey"`
+	TxID       string         `json:"txId"`
+}
+
+// GetReceiptProof returns the vote receipt for a commitment along with its
+// storage key and writing transaction. Requires the peer's history database.
+func (c *BallotContract) GetReceiptProof(
+	ctx contractapi.TransactionContextInterface,
+	commitmentHash string,
+) (*ReceiptProof, error) {
+	key, commitment, err := findVote(ctx, commitmentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	// History is returned newest first, so the first entry is the latest write.
+	if !iterator.HasNext() {
+		return nil, fmt.Errorf("no history for commitment")
+	}
+	latest, err := iterator.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReceiptProof{
+		Commitment: *commitment,
+		StorageKey: key,
+		TxID:       latest.GetTxId(),
+	}, nil
+}
+
+// GetReceiptByTxID returns the vote receipt written by a transaction, for
+// voters who kept only the transaction ID from their receipt.
+func (c *BallotContract) GetReceiptByTxID(
+	ctx contractapi.TransactionContextInterface,
+	txID string,
+) (*VoteCommitment, error) {
+	key, err := ctx.GetStub().GetState(voteTxIndexKey(txID))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("no vote recorded by transaction %s", txID)
+	}
+
+	bytes, err := ctx.GetStub().GetState(string(key))
+	if err != nil {
+		return nil, err
+	}
+	if bytes == nil {
+		return nil, fmt.Errorf("no vote recorded by transaction %s", txID)
+	}
+
+	var commitment VoteCommitment
+	if err := unmarshalState(bytes, &commitment); err != nil {
+		return nil, err
+	}
+	return &commitment, nil
+}
+
+// GetVotesBySubject returns the votes a subject cast in an election, and any
+// vote a proxy cast for them, so a verifier can confirm that they voted. Only
+// callers from an auditor organization may query it. The chosen option,
+// ranking and write-in text are cleared unless includeOption is set. Votes
+// cast before the subject index was introduced are not returned.
+func (c *BallotContract) GetVotesBySubject(
+	ctx contractapi.TransactionContextInterface,
+	electionID, subjectHash string,
+	includeOption bool,
+) ([]VoteCommitment, error) {
+	if err := requireMSP(ctx, auditorMSPs, "auditor"); err != nil {
+		return nil, err
+	}
+
+	keys, err := subjectVoteKeys(ctx, electionID, subjectHash)
+	if err != nil {
+		return nil, err
+	}
+
+	votes := []VoteCommitment{}
+	for _, key := range keys {
+		bytes, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, err
+		}
+		if bytes == nil {
+			continue
+		}
+
+		var vote VoteCommitment
+		if err := unmarshalState(bytes, &vote); err != nil {
+			return nil, err
+		}
+		if !includeOption {
+			vote.OptionID = ""
+			vote.Preferences = nil
+			vote.WriteIn = false
+			vote.WriteInText = ""
+			vote.VoterSignature = ""
+		}
+		votes = append(votes, vote)
+	}
+
+	return votes, nil
+}
+
+// optionIndexed reports whether a vote belongs in its option's
+// GetVotesByOption index: it carries an option, unlike a sealed vote still to
+// be revealed, and is not a provisional vote awaiting or denied confirmation.
+func (v *VoteCommitment) optionIndexed() bool {
+	return (!v.Sealed || v.Revealed) && (!v.Provisional || v.ProvisionalStatus == ProvisionalConfirmed)
+}
+
+// indexVoteOption records a vote under its option for GetVotesByOption.
+func indexVoteOption(ctx contractapi.TransactionContextInterface, vote *VoteCommitment) error {
+	indexKey, err := ctx.GetStub().CreateCompositeKey(voteOptionIndex, []string{vote.ElectionID, vote.tallyOption(), vote.CommitmentHash})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(indexKey, []byte{0x00})
+}
+
+// unindexVoteOption removes a vote from its option's GetVotesByOption index.
+func unindexVoteOption(ctx contractapi.TransactionContextInterface, vote *VoteCommitment) error {
+	indexKey, err := ctx.GetStub().CreateCompositeKey(voteOptionIndex, []string{vote.ElectionID, vote.tallyOption(), vote.CommitmentHash})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(indexKey)
+}
+
+// hasTallyOption reports whether optionID names something an election-wide
+// tally counts votes under: a configured option, "<contestID>/<optionID>" for
+// a contest option, a write-in, or an abstention.
+func (e *Election) hasTallyOption(optionID string) bool {
+	if optionID == AbstainOptionID || e.hasOption(optionID) || strings.HasPrefix(optionID, writeInOptionPrefix) {
+		return true
+	}
+	for _, contest := range e.Contests {
+		prefix := contest.ContestID + "/"
+		if !strings.HasPrefix(optionID, prefix) {
+			continue
+		}
+		option := strings.TrimPrefix(optionID, prefix)
+		if option == AbstainOptionID || containsOption(contest.Options, option) || strings.HasPrefix(option, writeInOptionPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetVotesByOption returns a page of the vote commitments recorded for one
+// option of an election, for manual audit sampling. In a multi-contest
+// election optionID is "<contestID>/<optionID>", as in TallyResults. An option
+// with no votes yields an empty page. Only callers from an auditor organization
+// may query it. Votes cast before the option index was introduced are not
+// returned.
+func (c *BallotContract) GetVotesByOption(
+	ctx contractapi.TransactionContextInterface,
+	electionID, optionID string,
+	pageSize int,
+	bookmark string,
+) (*VotePage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+	if err := requireMSP(ctx, auditorMSPs, "auditor"); err != nil {
+		return nil, err
+	}
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if !election.hasTallyOption(optionID) {
+		return nil, fmt.Errorf("unknown option %s for election %s", optionID, electionID)
+	}
+
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		voteOptionIndex, []string{electionID, optionID}, int32(pageSize), bookmark,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	votes := []VoteCommitment{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(record.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) != 3 {
+			continue
+		}
+
+		key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{electionID, parts[2]})
+		if err != nil {
+			return nil, err
+		}
+		bytes, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, err
+		}
+		if bytes == nil {
+			continue
+		}
+
+		var vote VoteCommitment
+		if err := unmarshalState(bytes, &vote); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+
+	return &VotePage{
+		Votes:        votes,
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: int(metadata.GetFetchedRecordsCount()),
+	}, nil
+}
+
+// GetVotesByElection returns a page of vote commitments recorded for an election.
+// Pass the returned bookmark to fetch the next page; an empty bookmark starts
+// from the beginning. Only callers from an auditor organization may query it.
+func (c *BallotContract) GetVotesByElection(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	pageSize int,
+	bookmark string,
+) (*VotePage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+	if err := requireMSP(ctx, auditorMSPs, "auditor"); err != nil {
+		return nil, err
+	}
+
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		voteObjectType, []string{electionID}, int32(pageSize), bookmark,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	votes := []VoteCommitment{}
+	for iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var vote VoteCommitment
+		if err := unmarshalState(record.Value, &vote); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+
+	return &VotePage{
+		Votes:        votes,
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: int(metadata.GetFetchedRecordsCount()),
+	}, nil
+}
+
+// InvalidateVote invalidates a subject's vote in an OPEN or PAUSED election so
+// they may vote again, for supervised re-votes such as when a voter reports
+// coercion. Every vote the subject has cast in the election that is not
+// already invalidated is marked Invalidated with the reason and official, which
+// keeps them in the key history, and the has-voted markers are cleared. This
+// includes the votes a proxy cast for their delegators, whose markers are
+// cleared too. Invalidated votes are not counted: they are removed from
+// GetVotesByOption, no longer count towards MaxVotesPerOption and are dropped
+// from GetAnomalousVotes. Only election officials may call it.
+// Emits a "VoteInvalidated" event.
+func (c *BallotContract) InvalidateVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID, subjectHash, reason, officialID string,
+) error {
+	if err := validateInputs(
+		requireKeyID("subject hash", subjectHash),
+		requireID("official ID", officialID),
+		maxLength("invalidation reason", reason, maxTextLength),
+	); err != nil {
+		return err
+	}
+	if reason == "" {
+		return codedErrorf(ErrCodeInvalidArgument, "invalidation reason is required")
+	}
+	if err := requireMSP(ctx, officialMSPs, "election official"); err != nil {
+		return err
+	}
+
+	election, err := getElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if election.Status != StatusOpen && election.Status != StatusPaused {
+		return codedErrorf(ErrCodeElectionNotOpen, "votes can only be invalidated while election %s is open (status %s)", electionID, election.Status)
+	}
+
+	// The votes a proxy cast for their delegators are indexed under the
+	// delegators
+	keys, err := subjectVoteKeys(ctx, electionID, subjectHash)
+	if err != nil {
+		return err
+	}
+	proxied, err := proxyDelegators(ctx, electionID, subjectHash)
+	if err != nil {
+		return err
+	}
+	for _, delegator := range proxied {
+		delegatorKeys, err := subjectVoteKeys(ctx, electionID, delegator)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, delegatorKeys...)
+	}
+
+	invalidated := []string{}
+	delegators := map[string]bool{}
+	for _, key := range keys {
+		bytes, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return err
+		}
+		if bytes == nil {
+			continue
+		}
+
+		var vote VoteCommitment
+		if err := unmarshalState(bytes, &vote); err != nil {
+			return err
+		}
+		// The subject's index also lists a vote their proxy cast for them,
+		// which is invalidated with the proxy's
+		if vote.Invalidated || vote.SubjectHash != subjectHash {
+			continue
+		}
+		vote.Invalidated = true
+		vote.InvalidatedReason = reason
+		vote.InvalidatedBy = officialID
+		bytes, err = marshalState(vote)
+		if err != nil {
+			return err
+		}
+		if err := ctx.GetStub().PutState(key, bytes); err != nil {
+			return err
+		}
+		if vote.optionIndexed() {
+			if err := unindexVoteOption(ctx, &vote); err != nil {
+				return err
+			}
+		}
+		if err := unflagOptionBurst(ctx, election, &vote); err != nil {
+			return err
+		}
+		if vote.DelegatedFrom != "" {
+			delegators[vote.DelegatedFrom] = true
+		} else if vote.ContestID != "" {
+			if err := ctx.GetStub().DelState(votedContestKey(electionID, vote.ContestID, subjectHash)); err != nil {
+				return err
+			}
+		}
+		invalidated = append(invalidated, vote.CommitmentHash)
+	}
+	if len(invalidated) == 0 {
+		return codedErrorf(ErrCodeNotFound, "no valid vote recorded for subject in election %s", electionID)
+	}
+
+	if err := ctx.GetStub().DelState(votedKey(electionID, subjectHash)); err != nil {
+		return err
+	}
+	for delegator := range delegators {
+		if err := ctx.GetStub().DelState(votedKey(electionID, delegator)); err != nil {
+			return err
+		}
+	}
+	if err := addToVoteCount(ctx, electionID, -1-len(delegators)); err != nil {
+		return err
+	}
+	if err := logEvent(ctx, electionID, EventVoteInvalidated, subjectHash); err != nil {
+		return err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	return emitEvent(ctx, EventVoteInvalidated, VoteInvalidatedEvent{
+		ElectionID:       electionID,
+		SubjectHash:      subjectHash,
+		CommitmentHashes: invalidated,
+		Reason:           reason,
+		OfficialID:       officialID,
+		TxID:             ctx.GetStub().GetTxID(),
+		Timestamp:        now.Format(time.RFC3339Nano),
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// subjectWeightKey holds the vote weight registered for a subject in a
+// weighted election.
+func subjectWeightKey(electionID, subjectHash string) string {
+	return fmt.Sprintf("weight:%s:%s", electionID, subjectHash)
+}
+
+// subjectWeight returns the weight a subject's vote carries: the weight
+// registered with RegisterWeightedSubject in a weighted election, and 1
+// otherwise.
+func subjectWeight(ctx contractapi.TransactionContextInterface, election *Election, subjectHash string) (int, error) {
+	if !election.Weighted {
+		return 1, nil
+	}
+
+	bytes, err := ctx.GetStub().GetState(subjectWeightKey(election.ElectionID, subjectHash))
+	if err != nil {
+		return 0, err
+	}
+	if bytes == nil {
+		return 0, codedErrorf(ErrCodeNotRegistered, "subject has no registered weight in weighted election %s", election.ElectionID)
+	}
+	weight, err := strconv.Atoi(string(bytes))
+	if err != nil {
+		return 0, fmt.Errorf("corrupt weight for subject %s: %w", subjectHash, err)
+	}
+	return weight, nil
+}
+
+// weight returns the weight a vote is counted with. Votes recorded before
+// weights were introduced count once.
+func (v *VoteCommitment) weight() int {
+	if v.Weight <= 0 {
+		return 1
+	}
+	return v.Weight
+}
+
+// RegisterWeightedSubject registers a subject for a weighted election with the
+// weight their vote carries, such as the number of shares they hold.
+// Registering an already registered subject replaces their weight while
+// registration is open, unless they have already voted. Only election
+// officials may call it.
+func (c *BallotContract) RegisterWeightedSubject(
+	ctx contractapi.TransactionContextInterface,
+	electionID, subjectHash string,
+	weight int,
+) error {
+	if weight <= 0 {
+		return codedErrorf(ErrCodeInvalidArgument, "weight must be positive")
+	}
+	if err := requireMSP(ctx, officialMSPs, "election official"); err != nil {
+		return err
+	}
+
+	election, err := requireRegistrationOpen(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if !election.Weighted {
+		return codedErrorf(ErrCodeInvalidStatus, "election %s is not weighted", electionID)
+	}
+	voted, err := ctx.GetStub().GetState(votedKey(electionID, subjectHash))
+	if err != nil {
+		return err
+	}
+	if voted != nil {
+		return codedErrorf(ErrCodeAlreadyVoted, "subject %s has already voted; their weight cannot change", subjectHash)
+	}
+
+	if _, err := registerSubject(ctx, electionID, subjectHash); err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(subjectWeightKey(electionID, subjectHash), []byte(strconv.Itoa(weight)))
+}
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestRegisterWeightedSubjectRequiresOfficial(t *testing.T) {
+	env := newTestEnv(t)
+	env.createElection("e1", `{"title":"Board","options":["yes","no"],"weighted":true}`)
+
+	env.setCaller("VoterOrgMSP")
+	err := env.invoke(func() error {
+		return env.contract.RegisterWeightedSubject(env.ctx, "e1", "subject1", 100)
+	})
+	wantCode(t, err, ErrCodeUnauthorized)
+}
+
+func TestRegisterWeightedSubjectKeepsVotedSubjectWeight(t *testing.T) {
+	env := newTestEnv(t)
+	env.createElection("e1", `{"title":"Board","options":["yes","no"],"weighted":true}`)
+	env.mustInvoke(func() error {
+		return env.contract.RegisterWeightedSubject(env.ctx, "e1", "subject1", 5)
+	})
+	env.mustInvoke(func() error {
+		return env.stub.PutState(votedKey("e1", "subject1"), []byte("tx0"))
+	})
+
+	err := env.invoke(func() error {
+		return env.contract.RegisterWeightedSubject(env.ctx, "e1", "subject1", 50)
+	})
+	wantCode(t, err, ErrCodeAlreadyVoted)
+
+	weight, err := env.stub.GetState(subjectWeightKey("e1", "subject1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(weight) != "5" {
+		t.Fatalf("got weight %s, want 5", weight)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// writeInOptionPrefix marks the synthetic option IDs write-in votes are
+// counted under. Configured options may not use it.
+const writeInOptionPrefix = "writein:"
+
+// normalizeWriteIn folds case and collapses whitespace so that write-ins for
+// the same candidate are counted together.
+func normalizeWriteIn(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// CastWriteInVote records a vote for a free-text candidate that is not one of
+// the election's options. The vote is counted under the synthetic option
+// "writein:<text>", with the text case-folded and whitespace collapsed, so
+// identical write-ins aggregate in TallyResults. Empty write-ins are rejected.
+func (c *BallotContract) CastWriteInVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID, subjectHash, commitmentHash, writeInText, metaJSON string,
+) error {
+	if err := validateInputs(maxLength("write-in text", writeInText, maxTextLength)); err != nil {
+		return err
+	}
+	normalized := normalizeWriteIn(writeInText)
+	if normalized == "" {
+		return codedErrorf(ErrCodeInvalidArgument, "write-in text is required")
+	}
+	if err := validateInputs(
+		maxLength("write-in text", normalized, maxIDLength-len(writeInOptionPrefix)),
+		maxLength("metadata", metaJSON, maxJSONLength),
+	); err != nil {
+		return err
+	}
+
+	var meta map[string]any
+	if err := json.Unmarshal([]byte(metaJSON), &meta); err != nil {
+		return withCode(ErrCodeInvalidArgument, err)
+	}
+
+	return recordVote(ctx, &VoteCommitment{
+		ElectionID:     electionID,
+		SubjectHash:    subjectHash,
+		CommitmentHash: commitmentHash,
+		OptionID:       writeInOptionPrefix + normalized,
+		WriteIn:        true,
+		WriteInText:    writeInText,
+		Meta:           meta,
+	})
+}
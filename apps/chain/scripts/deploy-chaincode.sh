@@ -16,7 +16,8 @@ peer lifecycle chaincode approveformyorg \
   --name ballot_cc \
   --version 1 \
   --package-id ballot_cc_1:$(openssl rand -hex 8) \
-  --sequence 1
+  --sequence 1 \
+  --init-required
 
 echo "Commit chaincode"
 peer lifecycle chaincode commit \
@@ -24,5 +25,15 @@ peer lifecycle chaincode commit \
   --name ballot_cc \
   --version 1 \
   --sequence 1 \
+  --init-required \
+  --peerAddresses localhost:7051 \
+  --orderer localhost:7050
+
+echo "Initialize chaincode (the invoking identity becomes the contract admin)"
+peer chaincode invoke \
+  --channelID election \
+  --name ballot_cc \
+  --isInit \
+  --ctor '{"function":"InitLedger","Args":[]}' \
   --peerAddresses localhost:7051 \
   --orderer localhost:7050